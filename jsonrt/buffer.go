@@ -43,6 +43,7 @@ type Buffer struct {
 	off       int               // read at &buf[off], write at &buf[len(buf)]
 	runeBytes [utf8.UTFMax]byte // avoid allocation of slice on each WriteByte or Rune
 	encoder   *json.Encoder
+	json5     bool // AppendKey and AppendEnd write JSON5, see SetJson5
 }
 
 // ErrTooLarge is passed to panic if memory cannot be allocated to store data in a buffer.
@@ -59,6 +59,55 @@ func (buf *Buffer) AppendJson(s []byte) {
 	WriteJson(buf, s)
 }
 
+// SetJson5 switches AppendKey and AppendEnd to writing JSON5, for
+// generated config files which are edited by hand later.
+func (buf *Buffer) SetJson5(on bool) {
+	buf.json5 = on
+}
+
+// AppendKey writes an object key and the colon after it. In JSON5 mode
+// keys which are identifiers are written without quotes.
+func (buf *Buffer) AppendKey(key string) {
+	if buf.json5 && isJson5Key(key) {
+		buf.WriteString(key)
+	} else {
+		buf.AppendJsonString(key)
+	}
+	buf.WriteByte(':')
+}
+
+// AppendEnd closes an object or array with c. Encoders write a ',' after
+// every element, in JSON mode the one after the last element is dropped
+// here, in JSON5 mode it is kept as a trailing comma.
+func (buf *Buffer) AppendEnd(c byte) {
+	if !buf.json5 && buf.Len() > 0 && buf.buf[len(buf.buf)-1] == ',' {
+		buf.Rewind(1)
+	}
+	buf.WriteByte(c)
+}
+
+// isJson5Key reports whether key can be written unquoted, so that the
+// JSON5 syntax of FFLexer reads it back as the same string.
+func isJson5Key(key string) bool {
+	if len(key) == 0 || (key[0] >= '0' && key[0] <= '9') {
+		return false
+	}
+
+	switch key {
+	case "true", "false", "null", "Infinity", "NaN":
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '$') {
+			return false
+		}
+	}
+
+	return true
+}
+
 /*
 func (buf *Buffer) Bytes() []byte {
 
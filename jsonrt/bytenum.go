@@ -23,9 +23,24 @@
 package jsonrt
 
 import (
+	"errors"
+
 	"github.com/smithfox/ffjson/jsonrt/internal"
 )
 
+// NumError is the error of a failed number conversion, its Err is
+// ErrRange or ErrSyntax.
+type NumError = internal.NumError
+
+var (
+	// ErrRange: the value is out of range for the target type.
+	ErrRange = internal.ErrRange
+	// ErrSyntax: the value does not have the right syntax.
+	ErrSyntax = internal.ErrSyntax
+	// ErrPrecision: the value would change when converted, see FFCoerce.
+	ErrPrecision = errors.New("value would lose precision")
+)
+
 func ParseFloat(s []byte, bitSize int) (f float64, err error) {
 	return internal.ParseFloat(s, bitSize)
 }
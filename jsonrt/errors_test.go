@@ -0,0 +1,117 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorsUnexpectedToken(t *testing.T) {
+	ffl := NewFFLexer([]byte(`: "x"`))
+	_, err := ffl.ScanIntValue(64)
+
+	var ute *UnexpectedTokenError
+	if !errors.As(err, &ute) {
+		t.Fatalf("expected an *UnexpectedTokenError, got %T: %v", err, err)
+	}
+	if ute.Expected != FFTok_integer || ute.Got != FFTok_string {
+		t.Fatalf("unexpected tokens: %v %v", ute.Expected, ute.Got)
+	}
+	if !errors.Is(err, FFErr_unexpected_token_type) {
+		t.Fatalf("expected %v to match FFErr_unexpected_token_type", err)
+	}
+
+	ffl = NewFFLexer([]byte(`1`))
+	_, err = ffl.ScanStringValue()
+	if !errors.As(err, &ute) || ute.Expected != FFTok_colon {
+		t.Fatalf("expected a missing colon, got %v", err)
+	}
+}
+
+func TestErrorsRange(t *testing.T) {
+	ffl := NewFFLexer([]byte(`: 300`))
+	_, err := ffl.ScanIntValue(8)
+
+	if !errors.Is(err, ErrRange) {
+		t.Fatalf("expected ErrRange, got %v", err)
+	}
+
+	var ne *NumError
+	if !errors.As(err, &ne) || ne.Num != "300" {
+		t.Fatalf("expected a *NumError, got %v", err)
+	}
+}
+
+func TestErrorsUnexpectedEOF(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ffl *FFLexer) error
+		in   string
+	}{
+		{"value", func(ffl *FFLexer) error { _, err := ffl.ScanIntValue(64); return err }, `:`},
+		{"colon", func(ffl *FFLexer) error { _, err := ffl.ScanToValue(); return err }, ``},
+		{"string", func(ffl *FFLexer) error { _, err := ffl.ScanStringValue(); return err }, `: "abc`},
+		{"skip", func(ffl *FFLexer) error { return ffl.SkipField(FFTok_left_bracket) }, `"a": [1, 2`},
+		{"capture", func(ffl *FFLexer) error { _, err := ffl.CaptureField(FFTok_left_brace); return err }, `1, {`},
+	}
+
+	for _, tt := range tests {
+		err := tt.run(NewFFLexer([]byte(tt.in)))
+		if !errors.Is(err, ErrUnexpectedEOF) {
+			t.Fatalf("%s: expected ErrUnexpectedEOF, got %v", tt.name, err)
+		}
+		if !errors.Is(err, FFErr_io) {
+			t.Fatalf("%s: expected %v to match FFErr_io", tt.name, err)
+		}
+	}
+}
+
+func TestErrorsKindSentinel(t *testing.T) {
+	ffl := NewFFLexer([]byte(`"\x"`))
+	err := ffl.WrapErr(scanToError(ffl))
+
+	if !errors.Is(err, FFErr_string_invalid_escaped_char) {
+		t.Fatalf("expected FFErr_string_invalid_escaped_char, got %v", err)
+	}
+	if errors.Is(err, FFErr_invalid_string) {
+		t.Fatalf("expected %v not to match FFErr_invalid_string", err)
+	}
+}
+
+func TestErrorsParser(t *testing.T) {
+	p := NewFFParser(NewFFLexer([]byte(`{"a" 1}`)))
+
+	var err error
+	for err == nil {
+		_, err = p.Next()
+	}
+
+	var ute *UnexpectedTokenError
+	if !errors.As(err, &ute) || ute.Expected != FFTok_colon || ute.Got != FFTok_integer {
+		t.Fatalf("expected a missing colon, got %v", err)
+	}
+
+	p = NewFFParser(NewFFLexer([]byte(`[1, 2`)))
+	err = nil
+	for err == nil {
+		_, err = p.Next()
+	}
+	if !errors.Is(err, ErrUnexpectedEOF) {
+		t.Fatalf("expected ErrUnexpectedEOF, got %v", err)
+	}
+}
@@ -23,6 +23,7 @@
 package jsonrt
 
 import (
+	"bytes"
 	"unicode/utf8"
 )
 
@@ -32,6 +33,46 @@ const (
 	smallLongEss = '\u017f'
 )
 
+// foldFunc returns one of four different case folding equivalence
+// functions, from most general (and slow) to fastest:
+//
+// 1) bytes.EqualFold, if the key s contains any non-ASCII UTF-8
+// 2) EqualFoldRight, if s contains special folding ASCII ('k', 'K', 's', 'S')
+// 3) AsciiEqualFold, no special, but includes non-letters (including _)
+// 4) SimpleLetterEqualFold, no specials, no non-letters.
+//
+// The letters S and K are special because they map to 3 runes, not just 2:
+//   - S maps to s and to U+017F 'ſ' Latin small letter long s
+//   - k maps to K and to U+212A 'K' Kelvin sign
+//
+// See https://play.golang.org/p/tTxjOc0OGo
+//
+// The returned function is specialized for matching against s and
+// should only be given s. It's not curried for performance reasons.
+func foldFunc(s []byte) func(s, t []byte) bool {
+	nonLetter := false
+	special := false // special letter
+	for _, b := range s {
+		if b >= utf8.RuneSelf {
+			return bytes.EqualFold
+		}
+		upper := b & caseMask
+		if upper < 'A' || upper > 'Z' {
+			nonLetter = true
+		} else if upper == 'K' || upper == 'S' {
+			// See above for why these letters are special.
+			special = true
+		}
+	}
+	if special {
+		return EqualFoldRight
+	}
+	if nonLetter {
+		return AsciiEqualFold
+	}
+	return SimpleLetterEqualFold
+}
+
 // equalFoldRight is a specialization of bytes.EqualFold when s is
 // known to be all ASCII (including punctuation), but contains an 's',
 // 'S', 'k', or 'K', requiring a Unicode fold on the bytes in t.
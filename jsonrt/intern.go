@@ -0,0 +1,103 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// InternTable hands out one string for all byte-identical values, so
+// decoding the same small set of strings again and again does not
+// allocate. It is safe for concurrent use, and can be shared by lexers,
+// see SetIntern.
+type InternTable struct {
+	hits   uint64 // first, for atomic on 32 bit platforms
+	misses uint64
+
+	mu         sync.RWMutex
+	strings    map[string]string
+	maxEntries int
+	maxLen     int
+}
+
+// InternStats are the counts of an InternTable.
+type InternStats struct {
+	Hits    uint64 // strings found in the table
+	Misses  uint64 // strings not found, which were allocated
+	Entries int
+}
+
+// NewInternTable returns an InternTable holding up to maxEntries strings
+// of up to maxLen bytes. Once it is full, it keeps the strings it has and
+// only allocates new ones.
+func NewInternTable(maxEntries, maxLen int) *InternTable {
+	return &InternTable{
+		strings:    make(map[string]string),
+		maxEntries: maxEntries,
+		maxLen:     maxLen,
+	}
+}
+
+// String returns b as a string, the one in the table if there is one.
+func (t *InternTable) String(b []byte) string {
+	if len(b) > t.maxLen {
+		return string(b)
+	}
+
+	t.mu.RLock()
+	s, ok := t.strings[string(b)]
+	t.mu.RUnlock()
+
+	if ok {
+		atomic.AddUint64(&t.hits, 1)
+		return s
+	}
+	atomic.AddUint64(&t.misses, 1)
+
+	s = string(b)
+	t.mu.Lock()
+	if len(t.strings) < t.maxEntries {
+		t.strings[s] = s
+	}
+	t.mu.Unlock()
+
+	return s
+}
+
+// Stats returns the counts of t.
+func (t *InternTable) Stats() InternStats {
+	t.mu.RLock()
+	n := len(t.strings)
+	t.mu.RUnlock()
+
+	return InternStats{
+		Hits:    atomic.LoadUint64(&t.hits),
+		Misses:  atomic.LoadUint64(&t.misses),
+		Entries: n,
+	}
+}
+
+// Reset empties t and its counts.
+func (t *InternTable) Reset() {
+	t.mu.Lock()
+	t.strings = make(map[string]string)
+	t.mu.Unlock()
+
+	atomic.StoreUint64(&t.hits, 0)
+	atomic.StoreUint64(&t.misses, 0)
+}
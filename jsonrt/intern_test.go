@@ -0,0 +1,89 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestInternTable(t *testing.T) {
+	it := NewInternTable(2, 8)
+	ffl := NewFFLexer([]byte(`: "OK", : "OK", : "FAILED", : "PENDING", : "PENDING", : "much too long"`))
+	ffl.SetIntern(it)
+
+	var got []string
+	for {
+		s, err := ffl.ScanStringValue()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, s)
+		if tok, _ := ffl.Scan(false); tok != FFTok_comma {
+			break
+		}
+	}
+
+	if len(got) != 6 || got[1] != "OK" || got[5] != "much too long" {
+		t.Fatalf("unexpected strings: %v", got)
+	}
+
+	st := it.Stats()
+	if st.Hits != 1 || st.Misses != 4 || st.Entries != 2 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+
+	it.Reset()
+	if st = it.Stats(); st.Hits != 0 || st.Entries != 0 {
+		t.Fatalf("unexpected stats after Reset: %+v", st)
+	}
+}
+
+func TestInternTableAllocs(t *testing.T) {
+	it := NewInternTable(16, 16)
+	it.String([]byte("DE"))
+
+	key := []byte("DE")
+	allocs := testing.AllocsPerRun(100, func() {
+		it.String(key)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations for a hit, got %v", allocs)
+	}
+}
+
+func TestInternTableConcurrent(t *testing.T) {
+	it := NewInternTable(100, 16)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				it.String([]byte(strings.Repeat("x", i%10)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	st := it.Stats()
+	if st.Hits+st.Misses != 8000 || st.Entries != 10 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
@@ -530,6 +530,36 @@ func atof64(s []byte) (f float64, err error) {
 	return f, err
 }
 
+// FloatFromParts returns mantissa*10^exp, negated if neg, as a float of
+// bitSize, for a number a lexer has split into those parts already. It
+// only takes the fast paths of ParseFloat, ok is false if they are not
+// enough, or the result is out of range, and ParseFloat must be used.
+func FloatFromParts(mantissa uint64, exp int, neg bool, bitSize int) (f float64, ok bool) {
+	flt := &float64info
+	if bitSize == 32 {
+		if f, ok := atof32exact(mantissa, exp, neg); ok {
+			return float64(f), true
+		}
+		flt = &float32info
+	} else if f, ok := atof64exact(mantissa, exp, neg); ok {
+		return f, true
+	}
+
+	var ext extFloat
+	if !ext.AssignDecimal(mantissa, exp, neg, false, flt) {
+		return 0, false
+	}
+	b, ovf := ext.floatBits(flt)
+	if ovf {
+		return 0, false
+	}
+
+	if bitSize == 32 {
+		return float64(math.Float32frombits(uint32(b))), true
+	}
+	return math.Float64frombits(b), true
+}
+
 // ParseFloat converts the string s to a floating-point number
 // with the precision specified by bitSize: 32 for float32, or 64 for float64.
 // When bitSize=32, the result still has type float64, but it will be
@@ -44,6 +44,10 @@ func (e *NumError) Error() string {
 	return "strconv." + e.Func + ": " + "parsing " + strconv.Quote(e.Num) + ": " + e.Err.Error()
 }
 
+func (e *NumError) Unwrap() error {
+	return e.Err
+}
+
 func syntaxError(fn, str string) *NumError {
 	return &NumError{fn, str, ErrSyntax}
 }
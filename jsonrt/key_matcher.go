@@ -0,0 +1,153 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// KeyMatcher finds the field a scanned object key belongs to, the way
+// encoding/json does: an exact match first, then a case insensitive one
+// using the fold function suited to each name. It is built once per type
+// and safe for concurrent use.
+type KeyMatcher struct {
+	names []string
+	keys  [][]byte
+	folds []func(s, t []byte) bool
+	exact map[string]int
+
+	fold      bool
+	lower     map[string]int // ASCII names by their lower case form
+	foldRight []int          // ASCII names a non-ASCII key may still match
+	unicode   []int          // non-ASCII names
+}
+
+// NewKeyMatcher returns a KeyMatcher for names, Match returns indexes into
+// it. When two names match a key, the first one wins.
+func NewKeyMatcher(names []string) *KeyMatcher {
+	km := newKeyMatcher(names)
+	km.fold = true
+	km.lower = make(map[string]int, len(names))
+
+	for i, key := range km.keys {
+		km.folds[i] = foldFunc(key)
+
+		if !isASCII(key) {
+			km.unicode = append(km.unicode, i)
+			continue
+		}
+
+		lower := string(asciiLower(nil, key))
+		if _, ok := km.lower[lower]; !ok {
+			km.lower[lower] = i
+		}
+		if bytes.ContainsAny(key, "kKsS") {
+			km.foldRight = append(km.foldRight, i)
+		}
+	}
+
+	return km
+}
+
+// NewExactKeyMatcher returns a KeyMatcher which only matches keys equal
+// to one of names.
+func NewExactKeyMatcher(names []string) *KeyMatcher {
+	return newKeyMatcher(names)
+}
+
+func newKeyMatcher(names []string) *KeyMatcher {
+	km := &KeyMatcher{
+		names: names,
+		keys:  make([][]byte, len(names)),
+		folds: make([]func(s, t []byte) bool, len(names)),
+		exact: make(map[string]int, len(names)),
+	}
+
+	for i := len(names) - 1; i >= 0; i-- {
+		km.keys[i] = []byte(names[i])
+		km.exact[names[i]] = i
+	}
+
+	return km
+}
+
+// Name returns the name of field i.
+func (km *KeyMatcher) Name(i int) string {
+	return km.names[i]
+}
+
+// Len returns the number of names.
+func (km *KeyMatcher) Len() int {
+	return len(km.names)
+}
+
+// Match returns the index of the name key matches, or -1.
+func (km *KeyMatcher) Match(key []byte) int {
+	if i, ok := km.exact[string(key)]; ok {
+		return i
+	}
+
+	if !km.fold {
+		return -1
+	}
+
+	found := -1
+	if isASCII(key) {
+		var buf [64]byte
+		if i, ok := km.lower[string(asciiLower(buf[:0], key))]; ok {
+			found = i
+		}
+	} else {
+		found = km.matchAny(km.foldRight, key, found)
+	}
+
+	return km.matchAny(km.unicode, key, found)
+}
+
+// matchAny returns the first of the fields in list, or found if it comes
+// earlier, which matches key.
+func (km *KeyMatcher) matchAny(list []int, key []byte, found int) int {
+	for _, i := range list {
+		if found >= 0 && i > found {
+			break
+		}
+		if km.folds[i](km.keys[i], key) {
+			return i
+		}
+	}
+	return found
+}
+
+func isASCII(s []byte) bool {
+	for _, c := range s {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func asciiLower(dst, s []byte) []byte {
+	for _, c := range s {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
@@ -0,0 +1,77 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"testing"
+)
+
+func TestKeyMatcher(t *testing.T) {
+	km := NewKeyMatcher([]string{"Name", "name_id", "Kind", "ID", "Id", "Straße"})
+
+	tests := []struct {
+		key string
+		i   int
+	}{
+		{"Name", 0},
+		{"NAME", 0},
+		{"name_ID", 1},
+		{"kind", 2},
+		{"Kind", 2}, // Kelvin sign
+		{"ID", 3},
+		{"Id", 4},
+		{"id", 3},
+		{"STRASSE", -1},
+		{"straße", 5},
+		{"nam", -1},
+		{"", -1},
+	}
+
+	for _, tt := range tests {
+		if i := km.Match([]byte(tt.key)); i != tt.i {
+			t.Fatalf("expected %q to match %d, got %d", tt.key, tt.i, i)
+		}
+	}
+}
+
+func TestKeyMatcherExact(t *testing.T) {
+	km := NewExactKeyMatcher([]string{"a", "B", "a"})
+
+	if i := km.Match([]byte("a")); i != 0 {
+		t.Fatalf("expected the first name to win, got %d", i)
+	}
+	if i := km.Match([]byte("b")); i != -1 {
+		t.Fatalf("expected no case insensitive match, got %d", i)
+	}
+	if km.Len() != 3 || km.Name(1) != "B" {
+		t.Fatalf("unexpected names: %d %s", km.Len(), km.Name(1))
+	}
+}
+
+func BenchmarkKeyMatcher(b *testing.B) {
+	names := make([]string, 64)
+	for i := range names {
+		names[i] = "field_" + string(rune('a'+i%26)) + string(rune('A'+i/26))
+	}
+	km := NewKeyMatcher(names)
+	key := []byte("FIELD_ZB")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		km.Match(key)
+	}
+}
@@ -39,6 +39,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/smithfox/ffjson/jsonrt/internal"
 )
 
 type FFParseState int
@@ -49,6 +54,8 @@ const (
 	FFParse_want_colon
 	FFParse_want_value
 	FFParse_after_value
+	FFParse_array_start
+	FFParse_end
 )
 
 type FFTok int
@@ -73,8 +80,14 @@ const (
 
 	FFTok_string FFTok = iota
 
-	/* comment tokens are not currently returned to the parser, ever */
+	/* the text of a comment is only in Output with SetKeepComments(true) */
 	FFTok_comment FFTok = iota
+
+	/* push mode only: the input fed so far ends inside a token */
+	FFTok_need_more FFTok = iota
+
+	/* an object key, only returned by FFParser, Scan returns FFTok_string */
+	FFTok_key FFTok = iota
 )
 
 type FFErrKind int
@@ -94,9 +107,12 @@ const (
 	FFErr_unallowed_comment              FFErrKind = iota
 	FFErr_incomplete_comment             FFErrKind = iota
 	FFErr_unexpected_token_type          FFErrKind = iota // TODO: improve this error
+	FFErr_number_leading_zero            FFErrKind = iota
+	FFErr_trailing_data                  FFErrKind = iota
+	FFErr_invalid_number                 FFErrKind = iota
+	FFErr_limit_exceeded                 FFErrKind = iota
 )
 
-// TODO(pquerna): return line number and offset.
 func (err FFErrKind) String() string {
 	switch err {
 	case FFErr_e_ok:
@@ -127,9 +143,23 @@ func (err FFErrKind) String() string {
 		return "ffjson: incomplete comment"
 	case FFErr_unexpected_token_type:
 		return "ffjson: unexpected token sequence"
+	case FFErr_number_leading_zero:
+		return "ffjson: number with leading zero"
+	case FFErr_trailing_data:
+		return "ffjson: data after top-level value"
+	case FFErr_invalid_number:
+		return "ffjson: invalid number"
+	case FFErr_limit_exceeded:
+		return "ffjson: limit exceeded"
 	}
 
-	panic(fmt.Sprintf("unknown FFLexer error type: %v ", err))
+	panic(fmt.Sprintf("unknown FFLexer error type: %d ", int(err)))
+}
+
+// Error makes the kinds usable as sentinels: errors.Is(err, FFErr_io)
+// matches any *FFError of that kind.
+func (err FFErrKind) Error() string {
+	return err.String()
 }
 
 type FFError struct {
@@ -141,10 +171,49 @@ func (err *FFError) Error() string {
 	return err.Err.Error()
 }
 
+func (err *FFError) Unwrap() error {
+	return err.Err
+}
+
+func (err *FFError) Is(target error) bool {
+	kind, ok := target.(FFErrKind)
+	return ok && kind == err.Kind
+}
+
 func NewFFError(kind FFErrKind) *FFError {
 	return &FFError{Kind: kind, Err: errors.New(kind.String())}
 }
 
+// ErrNeedMore is returned by Scan in push mode, together with
+// FFTok_need_more, when the input fed so far ends before the next token
+// is complete.
+var ErrNeedMore = errors.New("ffjson: need more input")
+
+// ErrUnexpectedEOF is wrapped in the FFErr_io errors returned when the
+// input ends inside a token or a value.
+var ErrUnexpectedEOF = errors.New("ffjson: unexpected EOF")
+
+func newEOFError() *FFError {
+	return &FFError{Kind: FFErr_io, Err: ErrUnexpectedEOF}
+}
+
+// FFSyntax selects the flavour of JSON a FFLexer accepts.
+type FFSyntax int
+
+const (
+	// what Scan always accepted: comments, \v and \f as whitespace, and
+	// leading zeros split into several numbers.
+	FFSyntax_default FFSyntax = iota
+	// RFC 8259 only, anything else is an error.
+	FFSyntax_strict FFSyntax = iota
+	// JSON5: single quoted strings, unquoted keys, hex numbers, +1, .5, 5.,
+	// Infinity and NaN. All of them are returned as the usual tokens, with
+	// numbers written to Output in a form ParseInt and ParseFloat accept.
+	// Trailing commas need no support from Scan, it does not check the
+	// order of tokens.
+	FFSyntax_json5 FFSyntax = iota
+)
+
 type FFLexer struct {
 	reader          *ffReader
 	Output          DecodingBuffer //Output仅仅是为了给本库调用者使用，保证  Output = outputbuf
@@ -152,6 +221,54 @@ type FFLexer struct {
 	Token           FFTok
 	lastCurrentChar int
 	buf             Buffer
+	syntax          FFSyntax
+	keepComments    bool
+	tokStart        int // offset of the first byte of the last token
+	limits          FFLimits
+	limited         bool // limits has a limit set
+	coerce          FFCoerce
+	intern          *InternTable
+	num             numParts // the number scanned last, if hasNum
+	hasNum          bool
+	noCopy          bool   // see ScanView
+	view            []byte // the string ScanView did not copy
+	hasView         bool
+	depth           int // objects and arrays open
+	tokens          int // tokens scanned so far
+	pending         pushPending
+	peekbuf         []byte // Output saved by Peek
+}
+
+// pushPending is a string or comment Scan stopped in for more input in
+// push mode. The next Scan goes on with it where it stopped, instead of
+// lexing it again from its first byte.
+type pushPending struct {
+	kind       int  // one of the pending_ constants, or pending_none
+	captureall bool // of the Scan for a string
+	keep       bool // the comment goes to Output
+	star       bool // the last byte of a /* */ comment so far is a '*'
+}
+
+const (
+	pending_none = iota
+	pending_string
+	pending_single_quoted
+	pending_line_comment
+	pending_block_comment
+)
+
+// FFLimits bounds what a lexer spends on hostile input, see SetLimits. A
+// zero field means no limit.
+type FFLimits struct {
+	MaxDepth        int // nesting of objects and arrays
+	MaxStringBytes  int // bytes of a single string or key, after unescaping
+	MaxNumberDigits int // bytes of a single number, including sign and exponent
+	MaxTokens       int // tokens in the whole input
+	MaxInputBytes   int // bytes of the whole input
+}
+
+func limitError(name string, max int) error {
+	return &FFError{Kind: FFErr_limit_exceeded, Err: fmt.Errorf("ffjson: %s limit of %d exceeded", name, max)}
 }
 
 func NewFFLexer(input []byte) *FFLexer {
@@ -166,11 +283,26 @@ func NewFFLexer(input []byte) *FFLexer {
 	return fl
 }
 
+// NewFFLexerReader returns a lexer that reads its input from rd on demand,
+// keeping only a sliding window of it in memory instead of the whole input.
+func NewFFLexerReader(rd io.Reader) *FFLexer {
+	fl := &FFLexer{
+		Token:     FFTok_init,
+		reader:    newffReaderIO(rd),
+		outputbuf: &Buffer{},
+	}
+	fl.Output = fl.outputbuf //very important!
+	return fl
+}
+
 type LexerError struct {
-	offset int
-	line   int
-	char   int
-	err    error
+	offset  int
+	line    int
+	char    int
+	err     error
+	path    string
+	snippet string
+	caret   int
 }
 
 // Reset the Lexer and add new input.
@@ -179,23 +311,330 @@ func (ffl *FFLexer) Reset(input []byte) {
 	ffl.reader.Reset(input)
 	ffl.lastCurrentChar = 0
 	ffl.outputbuf.Reset()
+	ffl.depth = 0
+	ffl.tokens = 0
+	ffl.pending = pushPending{}
+	ffl.view = nil
+	ffl.hasView = false
+	ffl.hasNum = false
+	ffl.tokStart = 0
+}
+
+// NewFFLexerPush returns a lexer in push mode: the input is handed in
+// piece by piece with Feed, and Scan returns FFTok_need_more and
+// ErrNeedMore instead of blocking or failing when a token is split
+// between pieces. After the next Feed, Scan goes on with a string or a
+// comment where it stopped, and lexes any other token, which is short,
+// again from its first byte, so nothing is lost. FeedEOF marks the end of
+// the input.
+//
+// Only Scan is resumable, helpers like ScanIntValue which scan several
+// tokens should only be used once enough input has been fed.
+func NewFFLexerPush() *FFLexer {
+	fl := &FFLexer{
+		Token:     FFTok_init,
+		reader:    newffReader(nil),
+		outputbuf: &Buffer{},
+	}
+	fl.reader.ResetPush()
+	fl.Output = fl.outputbuf //very important!
+	return fl
+}
+
+// ResetPush resets the Lexer to push mode, see NewFFLexerPush.
+func (ffl *FFLexer) ResetPush() {
+	ffl.Token = FFTok_init
+	ffl.reader.ResetPush()
+	ffl.lastCurrentChar = 0
+	ffl.outputbuf.Reset()
+	ffl.depth = 0
+	ffl.tokens = 0
+	ffl.pending = pushPending{}
+	ffl.view = nil
+	ffl.hasView = false
+	ffl.hasNum = false
+	ffl.tokStart = 0
+}
+
+// Feed appends input for a lexer in push mode, p is copied and may be
+// reused by the caller.
+func (ffl *FFLexer) Feed(p []byte) {
+	ffl.reader.feed(p)
+}
+
+// FeedEOF tells a lexer in push mode that no more input will be fed.
+func (ffl *FFLexer) FeedEOF() {
+	ffl.reader.closed = true
+}
+
+// SetSyntax selects the syntax accepted by Scan, it is kept over Reset.
+func (ffl *FFLexer) SetSyntax(syntax FFSyntax) {
+	ffl.syntax = syntax
+	ffl.reader.json5 = syntax == FFSyntax_json5
+	if syntax == FFSyntax_strict {
+		ffl.reader.ws = &strictWhitespaceLookupTable
+	} else {
+		ffl.reader.ws = &whitespaceLookupTable
+	}
+}
+
+// SetLimits makes Scan, and so everything built on it, fail with a
+// FFErr_limit_exceeded error once the input goes over one of the limits.
+// The limits are kept over Reset, the counts are not.
+func (ffl *FFLexer) SetLimits(limits FFLimits) {
+	ffl.limits = limits
+	ffl.limited = limits != (FFLimits{})
+	ffl.reader.maxString = limits.MaxStringBytes
+	ffl.reader.maxInput = limits.MaxInputBytes
+}
+
+// Limits returns the limits set with SetLimits.
+func (ffl *FFLexer) Limits() FFLimits {
+	return ffl.limits
+}
+
+// SetIntern makes ValueString, and so ScanStringValue and friends, take
+// strings from t instead of allocating them. A nil t turns it off. It is
+// kept over Reset.
+func (ffl *FFLexer) SetIntern(t *InternTable) {
+	ffl.intern = t
+}
+
+// SetKeepComments makes Scan write the text of comments, including the
+// comment markers, to Output when it returns FFTok_comment, so tooling
+// rewriting JSONC files can keep them. TokenPos tells where they were.
+func (ffl *FFLexer) SetKeepComments(keep bool) {
+	ffl.keepComments = keep
+}
+
+// TokenPos returns where the token returned by the last Scan started: the
+// offset in the input, the line and the column, both counted from 1.
+func (ffl *FFLexer) TokenPos() (offset int, line int, column int) {
+	line, char := ffl.reader.LineAt(ffl.tokStart)
+	return ffl.tokStart, line, char + 1
+}
+
+// ExpectEOF checks that only whitespace, and comments if the syntax allows
+// them, follow the value scanned last. Call it after decoding the top-level
+// value to reject trailing garbage or a second value.
+func (ffl *FFLexer) ExpectEOF() error {
+	for {
+		c, err := ffl.reader.ReadByteNoWS()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		ffl.unreadByte()
+		if c != '/' || ffl.syntax == FFSyntax_strict {
+			return NewFFError(FFErr_trailing_data)
+		}
+
+		_, err = ffl.Scan(false)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// FFMark is a lexer state saved by Mark.
+type FFMark struct {
+	pos             int
+	hold            int
+	token           FFTok
+	tokStart        int
+	lastCurrentChar int
+	depth           int
+	tokens          int
+	output          []byte
+	view            []byte
+	viewPos         int  // offset of view in the input, if viewHeld
+	viewHeld        bool // view is in the window, kept there from viewPos on
+	hasView         bool
+	num             numParts
+	hasNum          bool
+	pending         pushPending
+}
+
+// Mark saves the state of the lexer: the position in the input, Token and
+// Output, so a decoder can try one shape of a value and Restore the lexer
+// to try another. A lexer reading from an io.Reader or in push mode keeps
+// the input after the mark in memory until Release; marks must be
+// released in the reverse order they were made.
+func (ffl *FFLexer) Mark() FFMark {
+	var output []byte
+	if ffl.outputbuf.Len() > 0 {
+		output = append([]byte(nil), ffl.outputbuf.Bytes()...)
+	}
+	return ffl.mark(output)
+}
+
+// mark is Mark with output as the saved Output.
+func (ffl *FFLexer) mark(output []byte) FFMark {
+	r := ffl.reader
+	m := FFMark{
+		pos:             r.Pos(),
+		hold:            r.hold,
+		token:           ffl.Token,
+		tokStart:        ffl.tokStart,
+		lastCurrentChar: ffl.lastCurrentChar,
+		depth:           ffl.depth,
+		tokens:          ffl.tokens,
+		output:          output,
+		view:            ffl.view,
+		hasView:         ffl.hasView,
+		num:             ffl.num,
+		hasNum:          ffl.hasNum,
+		pending:         ffl.pending,
+	}
+
+	if r.hold < 0 || m.pos < r.hold {
+		r.hold = m.pos
+	}
+
+	// the window moves, a view of it is kept as an offset, with the
+	// input from there on.
+	if ffl.hasView && !r.inputView() {
+		if i, ok := r.windowIndex(ffl.view); ok {
+			m.viewPos = r.base + i
+			m.viewHeld = true
+			if m.viewPos < r.hold {
+				r.hold = m.viewPos
+			}
+		} else {
+			m.view = append([]byte(nil), ffl.view...)
+		}
+	}
+
+	return m
+}
+
+// Restore puts the lexer back into the state saved by m. The mark stays
+// valid, it can be restored again until it is released.
+func (ffl *FFLexer) Restore(m FFMark) {
+	ffl.reader.i = m.pos - ffl.reader.base
+	ffl.Token = m.token
+	ffl.tokStart = m.tokStart
+	ffl.lastCurrentChar = m.lastCurrentChar
+	ffl.depth = m.depth
+	ffl.tokens = m.tokens
+	ffl.outputbuf.Reset()
+	ffl.outputbuf.Write(m.output)
+	ffl.view = m.view
+	if m.viewHeld {
+		i := m.viewPos - ffl.reader.base
+		ffl.view = ffl.reader.s[i : i+len(m.view)]
+	}
+	ffl.hasView = m.hasView
+	ffl.num = m.num
+	ffl.hasNum = m.hasNum
+	ffl.pending = m.pending
+}
+
+// Release lets the lexer drop the input kept for m.
+func (ffl *FFLexer) Release(m FFMark) {
+	ffl.reader.hold = m.hold
+}
+
+// scanKeyPrefix consumes prefix, the `"key":` of an object key, if the
+// input continues with it after whitespace, like Scan would lex the key
+// and the colon. It only looks at the input already read, and leaves the
+// lexer as it is when it returns false.
+func (ffl *FFLexer) scanKeyPrefix(prefix []byte) (bool, error) {
+	r := ffl.reader
+	j := r.i
+	for j < r.l && r.ws[r.s[j]] {
+		j++
+	}
+
+	if !bytes.HasPrefix(r.s[j:r.l], prefix) {
+		return false, nil
+	}
+
+	if err := ffl.count(FFTok_string); err != nil {
+		return false, err
+	}
+	if err := ffl.count(FFTok_colon); err != nil {
+		return false, err
+	}
+
+	r.i = j + len(prefix)
+	ffl.tokStart = r.Pos() - 1
+	ffl.Token = FFTok_colon
+	ffl.outputbuf.Reset()
+	return true, nil
+}
+
+// Peek returns the next token, like Scan(false), without consuming it.
+// Token and Output are left as they were.
+func (ffl *FFLexer) Peek() (FFTok, error) {
+	// Output is saved in peekbuf, which is reused, so Peek does not
+	// allocate.
+	ffl.peekbuf = append(ffl.peekbuf[:0], ffl.outputbuf.Bytes()...)
+	m := ffl.mark(ffl.peekbuf)
+	tok, err := ffl.Scan(false)
+	ffl.Restore(m)
+	ffl.Release(m)
+	return tok, err
+}
+
+// ResetReader resets the Lexer to read new input from rd.
+func (ffl *FFLexer) ResetReader(rd io.Reader) {
+	ffl.Token = FFTok_init
+	ffl.reader.ResetIO(rd)
+	ffl.lastCurrentChar = 0
+	ffl.outputbuf.Reset()
+	ffl.depth = 0
+	ffl.tokens = 0
+	ffl.pending = pushPending{}
+	ffl.view = nil
+	ffl.hasView = false
+	ffl.hasNum = false
+	ffl.tokStart = 0
 }
 
 func (le *LexerError) Error() string {
+	if le.path != "" {
+		return fmt.Sprintf(`ffjson error: (%T)%s offset=%d line=%d char=%d path=%s`,
+			le.err, le.err.Error(),
+			le.offset, le.line, le.char, le.path)
+	}
 	return fmt.Sprintf(`ffjson error: (%T)%s offset=%d line=%d char=%d`,
 		le.err, le.err.Error(),
 		le.offset, le.line, le.char)
 }
 
+// WrapErr adds the position in the input to err, and, if the whole input
+// is still in memory, the JSON path of the value being decoded and an
+// excerpt of the input. See LexerError.
 func (ffl *FFLexer) WrapErr(err error) error {
-	line, char := ffl.reader.PosWithLine()
-	// TOOD: calcualte lines/characters based on offset
-	return &LexerError{
-		offset: ffl.reader.Pos(),
+	if le, ok := err.(*LexerError); ok {
+		// already wrapped, where it happened.
+		return le
+	}
+
+	r := ffl.reader
+	line, char := r.PosWithLine()
+	le := &LexerError{
+		offset: r.Pos(),
 		line:   line,
 		char:   char,
 		err:    err,
 	}
+
+	if r.base == 0 {
+		le.path = jsonPathAt(r.s[:r.i], ffl.syntax)
+	}
+
+	at := ffl.tokStart - r.base
+	if at < 0 || at > r.i {
+		at = r.i
+	}
+	le.snippet, le.caret = snippetAt(r.s[:r.l], at)
+
+	return le
 }
 
 func (ffl *FFLexer) scanReadByte(captureall bool) (byte, error) {
@@ -218,12 +657,16 @@ func (ffl *FFLexer) readByte() (byte, error) {
 
 	c, err := ffl.reader.ReadByte()
 	if err != nil {
-		return 0, NewFFError(FFErr_io)
+		return ffl.readErr(err)
 	}
 
 	return c, nil
 }
 
+func (ffl *FFLexer) readErr(err error) (byte, error) {
+	return 0, ioError(err)
+}
+
 func (ffl *FFLexer) unreadByte() {
 	ffl.reader.UnreadByte()
 }
@@ -247,116 +690,241 @@ func (ffl *FFLexer) wantBytes(want []byte, iftrue FFTok) (FFTok, error) {
 	return iftrue, nil
 }
 
-func (ffl *FFLexer) lexComment() (FFTok, error) {
+// lexComment lexes a comment after its first '/'. If keep is set, the
+// text of the comment, without the line break ending a // comment, is
+// written to the output.
+func (ffl *FFLexer) lexComment(keep bool) (FFTok, error) {
 	c, err := ffl.readByte()
 	if err != nil {
 		return FFTok_error, err
 	}
 
+	if keep && (c == '/' || c == '*') {
+		ffl.outputbuf.WriteByte('/')
+		ffl.outputbuf.WriteByte(c)
+	}
+
 	if c == '/' {
-		// a // comment, scan until line ends.
-		for {
-			c, err := ffl.readByte()
-			if err != nil {
-				return FFTok_error, err
+		return ffl.lexLineComment(keep)
+	} else if c == '*' {
+		return ffl.lexBlockComment(keep, false)
+	} else {
+		return FFTok_error, NewFFError(FFErr_incomplete_comment)
+	}
+}
+
+// lexLineComment lexes a // comment after the //.
+func (ffl *FFLexer) lexLineComment(keep bool) (FFTok, error) {
+	// scan until line ends, or the input does.
+	var eof bool
+	for {
+		c, err := ffl.readByteEOF(&eof)
+		if err != nil {
+			if err == ErrNeedMore {
+				ffl.pending = pushPending{kind: pending_line_comment, keep: keep}
 			}
+			return FFTok_error, err
+		}
 
-			if c == '\n' {
-				return FFTok_comment, nil
+		if c == '\n' || eof {
+			if keep && ffl.outputbuf.Len() > 0 && ffl.outputbuf.Bytes()[ffl.outputbuf.Len()-1] == '\r' {
+				ffl.outputbuf.Rewind(1)
 			}
+			return FFTok_comment, nil
 		}
-	} else if c == '*' {
-		// a /* */ comment, scan */
-		for {
+
+		if keep {
+			ffl.outputbuf.WriteByte(c)
+		}
+	}
+}
+
+// lexBlockComment lexes a /* */ comment after the /*, or after a '*' in
+// it if star is set.
+func (ffl *FFLexer) lexBlockComment(keep bool, star bool) (FFTok, error) {
+	// scan */
+	for {
+		if !star {
 			c, err := ffl.readByte()
 			if err != nil {
+				if err == ErrNeedMore {
+					ffl.pending = pushPending{kind: pending_block_comment, keep: keep}
+				}
 				return FFTok_error, err
 			}
 
-			if c == '*' {
-				c, err := ffl.readByte()
+			if keep {
+				ffl.outputbuf.WriteByte(c)
+			}
 
-				if err != nil {
-					return FFTok_error, err
-				}
+			if c != '*' {
+				continue
+			}
+		}
+		star = false
 
-				if c == '/' {
-					return FFTok_comment, nil
-				}
+		c, err := ffl.readByte()
+		if err != nil {
+			if err == ErrNeedMore {
+				ffl.pending = pushPending{kind: pending_block_comment, keep: keep, star: true}
+			}
+			return FFTok_error, err
+		}
 
-				return FFTok_error, NewFFError(FFErr_incomplete_comment)
+		if c == '/' {
+			if keep {
+				ffl.outputbuf.WriteByte(c)
 			}
+			return FFTok_comment, nil
 		}
-	} else {
-		return FFTok_error, NewFFError(FFErr_incomplete_comment)
+
+		// not the end yet, and c may be the '*' of "**/".
+		ffl.unreadByte()
 	}
 }
 
 func (ffl *FFLexer) lexString(captureall bool) (FFTok, error) {
+	if ffl.noCopy && !captureall {
+		if v, ok := ffl.reader.viewString(); ok {
+			if ffl.syntax == FFSyntax_strict && !utf8.Valid(v) {
+				return FFTok_error, NewFFError(FFErr_string_invalid_utf8)
+			}
+			ffl.view = v
+			ffl.hasView = true
+			return FFTok_string, nil
+		}
+	}
+
 	if captureall {
 		ffl.buf.Reset()
-		err := ffl.reader.SliceString(&ffl.buf)
+	}
+	return ffl.lexStringRest(captureall)
+}
 
-		if err != nil {
-			return FFTok_error, err
+// lexStringRest lexes a string after its opening quote, or goes on with
+// the part of it in Output, or in buf for captureall, in push mode.
+func (ffl *FFLexer) lexStringRest(captureall bool) (FFTok, error) {
+	out := ffl.outputbuf
+	if captureall {
+		out = &ffl.buf
+	}
+
+	err := ffl.reader.sliceString(out, 0)
+	if err != nil {
+		if err == ErrNeedMore {
+			ffl.pending = pushPending{kind: pending_string, captureall: captureall}
 		}
+		return FFTok_error, ioError(err)
+	}
 
+	if ffl.syntax == FFSyntax_strict && !utf8.Valid(out.Bytes()) {
+		return FFTok_error, NewFFError(FFErr_string_invalid_utf8)
+	}
+
+	if captureall {
 		WriteJson(ffl.outputbuf, ffl.buf.Bytes())
+	}
 
-		return FFTok_string, nil
-	} else {
-		err := ffl.reader.SliceString(ffl.outputbuf)
+	return FFTok_string, nil
+}
 
-		if err != nil {
-			return FFTok_error, err
-		}
+// readByteEOF is readByte for tokens the end of the input may end, numbers
+// and JSON5 identifiers: the end is returned as a 0 byte, which no such
+// token contains, and eof is set so unreadByteEOF does not unread it.
+func (ffl *FFLexer) readByteEOF(eof *bool) (byte, error) {
+	c, err := ffl.reader.ReadByte()
+	if err == io.EOF {
+		*eof = true
+		return 0, nil
+	}
+	if err != nil {
+		return ffl.readErr(err)
+	}
+	return c, nil
+}
 
-		return FFTok_string, nil
+func (ffl *FFLexer) unreadByteEOF(eof bool) {
+	if !eof {
+		ffl.unreadByte()
 	}
 }
 
 func (ffl *FFLexer) lexNumber() (FFTok, error) {
+	if tok, ok := ffl.lexNumberFast(); ok {
+		return tok, nil
+	}
+
 	var numRead int = 0
+	var eof bool
+	var leadingDot bool
 	tok := FFTok_integer
+	json5 := ffl.syntax == FFSyntax_json5
+	start := ffl.outputbuf.Len()
 
 	c, err := ffl.readByte()
 	if err != nil {
 		return FFTok_error, err
 	}
 
-	/* optional leading minus */
-	if c == '-' {
-		ffl.outputbuf.WriteByte(c)
-		c, err = ffl.readByte()
+	/* optional leading minus, JSON5 also has a plus */
+	if c == '-' || (c == '+' && json5) {
+		if c == '-' {
+			ffl.outputbuf.WriteByte(c)
+		}
+		c, err = ffl.readByteEOF(&eof)
 		if err != nil {
 			return FFTok_error, err
 		}
 	}
 
+	if json5 && (c == 'I' || c == 'N') {
+		ffl.outputbuf.WriteByte(c)
+		if c == 'I' {
+			return ffl.wantBytes(infinity_bytes1, FFTok_double)
+		}
+		return ffl.wantBytes(nan_bytes1, FFTok_double)
+	}
+
 	/* a single zero, or a series of integers */
 	if c == '0' {
 		ffl.outputbuf.WriteByte(c)
-		c, err = ffl.readByte()
+		c, err = ffl.readByteEOF(&eof)
 		if err != nil {
 			return FFTok_error, err
 		}
+
+		if c >= '0' && c <= '9' && ffl.syntax == FFSyntax_strict {
+			ffl.unreadByteEOF(eof)
+			return FFTok_error, NewFFError(FFErr_number_leading_zero)
+		}
+
+		if json5 && (c == 'x' || c == 'X') {
+			return ffl.lexHex()
+		}
 	} else if c >= '1' && c <= '9' {
 		for c >= '0' && c <= '9' {
 			ffl.outputbuf.WriteByte(c)
-			c, err = ffl.readByte()
+			if err = ffl.numberLimit(start); err != nil {
+				return FFTok_error, err
+			}
+			c, err = ffl.readByteEOF(&eof)
 			if err != nil {
 				return FFTok_error, err
 			}
 		}
+	} else if c == '.' && json5 {
+		/* .5 is written as 0.5 */
+		ffl.outputbuf.WriteByte('0')
+		leadingDot = true
 	} else {
-		ffl.unreadByte()
+		ffl.unreadByteEOF(eof)
 		return FFTok_error, NewFFError(FFErr_missing_integer_after_minus)
 	}
 
 	if c == '.' {
 		numRead = 0
 		ffl.outputbuf.WriteByte(c)
-		c, err = ffl.readByte()
+		c, err = ffl.readByteEOF(&eof)
 		if err != nil {
 			return FFTok_error, err
 		}
@@ -364,16 +932,24 @@ func (ffl *FFLexer) lexNumber() (FFTok, error) {
 		for c >= '0' && c <= '9' {
 			ffl.outputbuf.WriteByte(c)
 			numRead++
-			c, err = ffl.readByte()
+			if err = ffl.numberLimit(start); err != nil {
+				return FFTok_error, err
+			}
+			c, err = ffl.readByteEOF(&eof)
 			if err != nil {
 				return FFTok_error, err
 			}
 		}
 
 		if numRead == 0 {
-			ffl.unreadByte()
+			if json5 && !leadingDot {
+				/* 5. is written as 5.0 */
+				ffl.outputbuf.WriteByte('0')
+			} else {
+				ffl.unreadByteEOF(eof)
 
-			return FFTok_error, NewFFError(FFErr_missing_integer_after_decimal)
+				return FFTok_error, NewFFError(FFErr_missing_integer_after_decimal)
+			}
 		}
 
 		tok = FFTok_double
@@ -384,7 +960,7 @@ func (ffl *FFLexer) lexNumber() (FFTok, error) {
 		numRead = 0
 		ffl.outputbuf.WriteByte(c)
 
-		c, err = ffl.readByte()
+		c, err = ffl.readByteEOF(&eof)
 		if err != nil {
 			return FFTok_error, err
 		}
@@ -392,7 +968,7 @@ func (ffl *FFLexer) lexNumber() (FFTok, error) {
 		/* optional sign */
 		if c == '+' || c == '-' {
 			ffl.outputbuf.WriteByte(c)
-			c, err = ffl.readByte()
+			c, err = ffl.readByteEOF(&eof)
 			if err != nil {
 				return FFTok_error, err
 			}
@@ -401,7 +977,10 @@ func (ffl *FFLexer) lexNumber() (FFTok, error) {
 		for c >= '0' && c <= '9' {
 			ffl.outputbuf.WriteByte(c)
 			numRead++
-			c, err = ffl.readByte()
+			if err = ffl.numberLimit(start); err != nil {
+				return FFTok_error, err
+			}
+			c, err = ffl.readByteEOF(&eof)
 			if err != nil {
 				return FFTok_error, err
 			}
@@ -414,150 +993,506 @@ func (ffl *FFLexer) lexNumber() (FFTok, error) {
 		tok = FFTok_double
 	}
 
-	ffl.unreadByte()
+	ffl.unreadByteEOF(eof)
+
+	if err = ffl.numberLimit(start); err != nil {
+		return FFTok_error, err
+	}
 
 	return tok, nil
 }
 
-var true_bytes = []byte{'t', 'r', 'u', 'e'}
-var false_bytes = []byte{'f', 'a', 'l', 's', 'e'}
-var true_bytes1 = []byte{'r', 'u', 'e'}
-var false_bytes1 = []byte{'a', 'l', 's', 'e'}
-var null_bytes = []byte{'u', 'l', 'l'}
+// numberLimit checks the bytes of a number written to outputbuf since
+// start against FFLimits.MaxNumberDigits, as they are written, so an
+// endless number is not copied first.
+func (ffl *FFLexer) numberLimit(start int) error {
+	if max := ffl.limits.MaxNumberDigits; max > 0 && ffl.outputbuf.Len()-start > max {
+		return limitError("number", max)
+	}
+	return nil
+}
 
-//预期类似   : 8
-func (ffl *FFLexer) ScanIntValue(bitSize int) (int64, error) {
-	tok, err := ffl.Scan(false)
-	if err != nil {
-		return 0, err
+// numParts is a number split into mantissa*10^exp by lexNumberFast, so
+// the Value helpers need not parse its text again. ok is false if the
+// mantissa has more digits than fit.
+type numParts struct {
+	mant uint64
+	exp  int
+	neg  bool
+	ok   bool
+}
+
+// maxMantDigits is the number of decimal digits which always fit into the
+// uint64 mantissa.
+const maxMantDigits = 19
+
+// lexNumberFast lexes a number directly from the window of the reader,
+// accumulating its parts while checking it, and copies its text to the
+// output at once. It only takes the common case of a number which ends in
+// the window and is valid; for anything else it returns false, leaving
+// the reader as it was, and lexNumber takes the byte by byte way.
+func (ffl *FFLexer) lexNumberFast() (FFTok, bool) {
+	r := ffl.reader
+	s := r.s[:r.l]
+	j := r.i
+	tok := FFTok_integer
+
+	// the parts are read like readFloat in internal does: leading zeros
+	// only move the decimal point dp, digits after the first 19 only
+	// count, unless they are not zero and so truncated.
+	var n numParts
+	nd, ndMant, dp := 0, 0, 0
+	trunc := false
+
+	if j < len(s) && s[j] == '-' {
+		n.neg = true
+		j++
 	}
 
-	if tok != FFTok_colon { //预期是冒号
-		return 0, fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", FFTok_colon, tok, ffl.Output.String())
+	if j >= len(s) {
+		return FFTok_error, false
+	}
+	if s[j] == '0' {
+		j++
+		if j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			return FFTok_error, false
+		}
+	} else if s[j] >= '1' && s[j] <= '9' {
+		for ; j < len(s); j++ {
+			c := s[j] - '0'
+			if c > 9 {
+				break
+			}
+			nd++
+			if ndMant < maxMantDigits {
+				n.mant = n.mant*10 + uint64(c)
+				ndMant++
+			} else if c != 0 {
+				trunc = true
+			}
+		}
+	} else {
+		return FFTok_error, false
+	}
+	dp = nd
+
+	if j < len(s) && s[j] == '.' {
+		j++
+		start := j
+		for ; j < len(s); j++ {
+			c := s[j] - '0'
+			if c > 9 {
+				break
+			}
+			if c == 0 && nd == 0 {
+				dp--
+				continue
+			}
+			nd++
+			if ndMant < maxMantDigits {
+				n.mant = n.mant*10 + uint64(c)
+				ndMant++
+			} else if c != 0 {
+				trunc = true
+			}
+		}
+		if j == start {
+			return FFTok_error, false
+		}
+		tok = FFTok_double
+	}
+	n.exp = dp - ndMant
+
+	if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+		j++
+		eneg := false
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			eneg = s[j] == '-'
+			j++
+		}
+		start := j
+		e := 0
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			if e < 10000 {
+				e = e*10 + int(s[j]-'0')
+			}
+			j++
+		}
+		if j == start {
+			return FFTok_error, false
+		}
+		if eneg {
+			e = -e
+		}
+		n.exp += e
+		tok = FFTok_double
 	}
 
-	tok, err = ffl.Scan(false)
+	if j >= len(s) {
+		// the number may go on in input not read yet.
+		if !r.inputView() {
+			return FFTok_error, false
+		}
+	} else if c := s[j]; c == '.' || c == 'x' || c == 'X' || json5IdentByte(c) && ffl.syntax == FFSyntax_json5 {
+		// 5. and hex in JSON5, or errors.
+		return FFTok_error, false
+	}
 
-	if err != nil {
-		return 0, err
+	if max := ffl.limits.MaxNumberDigits; max > 0 && j-r.i > max {
+		return FFTok_error, false
 	}
 
-	if tok != FFTok_integer { //预期是 int
-		return 0, fmt.Errorf("ffjson: wanted int value, but got token: %v", tok)
+	n.ok = !trunc
+	ffl.outputbuf.Write(s[r.i:j])
+	r.i = j
+	ffl.num = n
+	ffl.hasNum = true
+	return tok, true
+}
+
+// int returns the integer n is, if it fits into bitSize bits.
+func (n *numParts) int(bitSize int) (int64, bool) {
+	if !n.ok || n.exp != 0 {
+		return 0, false
+	}
+	if bitSize == 0 {
+		bitSize = strconv.IntSize
 	}
 
-	return ParseInt(ffl.Output.Bytes(), 10, bitSize)
+	cutoff := uint64(1) << uint(bitSize-1)
+	if n.neg {
+		if n.mant > cutoff {
+			return 0, false
+		}
+		// for the cutoff itself, int64(n.mant) is negative already.
+		return -int64(n.mant), true
+	}
+
+	if n.mant >= cutoff {
+		return 0, false
+	}
+	return int64(n.mant), true
 }
 
-func (ffl *FFLexer) ScanUintValue(bitSize int) (uint64, error) {
-	tok, err := ffl.Scan(false)
-	if err != nil {
-		return 0, err
+// uint returns the unsigned integer n is, if it fits into bitSize bits.
+func (n *numParts) uint(bitSize int) (uint64, bool) {
+	if !n.ok || n.exp != 0 || n.neg {
+		return 0, false
+	}
+	if bitSize == 0 {
+		bitSize = strconv.IntSize
 	}
 
-	if tok != FFTok_colon { //预期是冒号
-		return 0, fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", FFTok_colon, tok, ffl.Output.String())
+	if bitSize < 64 && n.mant >= uint64(1)<<uint(bitSize) {
+		return 0, false
+	}
+	return n.mant, true
+}
+
+// float returns n as a float of bitSize, if it can do so cheaply.
+func (n *numParts) float(bitSize int) (float64, bool) {
+	if !n.ok {
+		return 0, false
+	}
+	if n.mant == 0 {
+		if n.neg {
+			return math.Copysign(0, -1), true
+		}
+		return 0, true
 	}
+	return internal.FloatFromParts(n.mant, n.exp, n.neg, bitSize)
+}
 
-	tok, err = ffl.Scan(false)
+// lexHex lexes the digits of a JSON5 hex number, the 0 before the x is
+// already in the output. The number is written in decimal instead, so
+// ParseInt and friends do not need to know about hex.
+func (ffl *FFLexer) lexHex() (FFTok, error) {
+	var eof bool
+	var v uint64
+	n := 0
 
-	if err != nil {
-		return 0, err
+	for {
+		c, err := ffl.readByteEOF(&eof)
+		if err != nil {
+			return FFTok_error, err
+		}
+
+		d, ok := hexDigit(c)
+		if !ok {
+			break
+		}
+
+		if v>>60 != 0 {
+			return FFTok_error, NewFFError(FFErr_invalid_number)
+		}
+		v = v<<4 | d
+		n++
 	}
 
-	if tok != FFTok_integer { //预期是 int
-		return 0, fmt.Errorf("ffjson: wanted uint value, but got token: %v", tok)
+	ffl.unreadByteEOF(eof)
+
+	if n == 0 {
+		return FFTok_error, NewFFError(FFErr_invalid_number)
 	}
 
-	return ParseUint(ffl.Output.Bytes(), 10, bitSize)
+	ffl.outputbuf.Rewind(1)
+	ffl.outputbuf.AppendUint(v, 10)
+
+	return FFTok_integer, nil
 }
 
-func (ffl *FFLexer) ScanStringValue() (string, error) {
-	tok, err := ffl.Scan(false)
-	if err != nil {
-		return "", err
+func hexDigit(c byte) (uint64, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return uint64(c - '0'), true
+	case c >= 'a' && c <= 'f':
+		return uint64(c - 'a' + 10), true
+	case c >= 'A' && c <= 'F':
+		return uint64(c - 'A' + 10), true
 	}
+	return 0, false
+}
 
-	if tok != FFTok_colon { //预期是冒号
-		return "", fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", FFTok_colon, tok, ffl.Output.String())
+// json5Start reports whether c starts a token only JSON5 has, or one
+// that is lexed differently there.
+func json5Start(c byte) bool {
+	return c == '\'' || c == '+' || c == '.' || json5IdentByte(c) && !(c >= '0' && c <= '9')
+}
+
+func json5IdentByte(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		c == '_' || c == '$' || c >= utf8.RuneSelf
+}
+
+func (ffl *FFLexer) lexJSON5(c byte, captureall bool) (FFTok, error) {
+	switch c {
+	case '\'':
+		return ffl.lexSingleQuoted(captureall)
+	case '+', '.':
+		ffl.unreadByte()
+		return ffl.lexNumber()
 	}
+	return ffl.lexIdent(c, captureall)
+}
 
-	tok, err = ffl.Scan(false)
+// lexSingleQuoted is lexString for JSON5 single quoted strings.
+func (ffl *FFLexer) lexSingleQuoted(captureall bool) (FFTok, error) {
+	if captureall {
+		ffl.buf.Reset()
+	}
+	return ffl.lexSingleQuotedRest(captureall)
+}
+
+// lexSingleQuotedRest is lexStringRest for JSON5 single quoted strings.
+func (ffl *FFLexer) lexSingleQuotedRest(captureall bool) (FFTok, error) {
+	out := ffl.outputbuf
+	if captureall {
+		out = &ffl.buf
+	}
 
+	err := ffl.reader.sliceStringSingle(out, 0)
 	if err != nil {
-		return "", err
+		if err == ErrNeedMore {
+			ffl.pending = pushPending{kind: pending_single_quoted, captureall: captureall}
+		}
+		return FFTok_error, ioError(err)
 	}
 
-	if tok != FFTok_string { //预期是 int
-		return "", fmt.Errorf("ffjson: wanted string value, but got token: %v", tok)
+	if captureall {
+		WriteJson(ffl.outputbuf, ffl.buf.Bytes())
 	}
 
-	return string(ffl.Output.Bytes()), nil
+	return FFTok_string, nil
 }
 
-func (ffl *FFLexer) ScanBoolValue(bitSize int) (bool, error) {
-	tok, err := ffl.Scan(false)
+// lexIdent lexes a JSON5 identifier: an unquoted key, returned as a
+// string, or one of true, false, null, Infinity and NaN.
+func (ffl *FFLexer) lexIdent(c byte, captureall bool) (FFTok, error) {
+	var eof bool
+	var err error
+
+	ffl.buf.Reset()
+	for json5IdentByte(c) {
+		ffl.buf.WriteByte(c)
+		if max := ffl.limits.MaxStringBytes; max > 0 && ffl.buf.Len() > max {
+			return FFTok_error, limitError("string", max)
+		}
+		c, err = ffl.readByteEOF(&eof)
+		if err != nil {
+			return FFTok_error, err
+		}
+	}
+	ffl.unreadByteEOF(eof)
+
+	id := ffl.buf.Bytes()
+	tok := FFTok_string
+	switch {
+	case bytes.Equal(id, true_bytes), bytes.Equal(id, false_bytes):
+		tok = FFTok_bool
+	case bytes.Equal(id, json5_null_bytes):
+		tok = FFTok_null
+	case bytes.Equal(id, json5_infinity_bytes), bytes.Equal(id, json5_nan_bytes):
+		tok = FFTok_double
+	}
+
+	if tok == FFTok_string && captureall {
+		WriteJson(ffl.outputbuf, id)
+	} else {
+		ffl.outputbuf.Write(id)
+	}
+
+	return tok, nil
+}
+
+var true_bytes = []byte{'t', 'r', 'u', 'e'}
+var false_bytes = []byte{'f', 'a', 'l', 's', 'e'}
+var true_bytes1 = []byte{'r', 'u', 'e'}
+var false_bytes1 = []byte{'a', 'l', 's', 'e'}
+var null_bytes = []byte{'u', 'l', 'l'}
+var json5_null_bytes = []byte("null")
+var json5_infinity_bytes = []byte("Infinity")
+var json5_nan_bytes = []byte("NaN")
+var infinity_bytes1 = []byte("nfinity")
+var nan_bytes1 = []byte("aN")
+
+// scanValue scans a token inside a value, skipping comments, where the
+// end of the input is an ErrUnexpectedEOF.
+func (ffl *FFLexer) scanValue() (FFTok, error) {
+	for {
+		tok, err := ffl.Scan(false)
+		if tok == FFTok_eof {
+			return FFTok_error, newEOFError()
+		}
+		if tok != FFTok_comment || err != nil {
+			return tok, err
+		}
+	}
+}
+
+// scanFieldValue scans the ':' and the value of a field, the value can
+// then be read by the Value helpers.
+func (ffl *FFLexer) scanFieldValue() error {
+	tok, err := ffl.scanValue()
 	if err != nil {
-		return false, err
+		return err
 	}
 
 	if tok != FFTok_colon { //预期是冒号
-		return false, fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", FFTok_colon, tok, ffl.Output.String())
+		return &UnexpectedTokenError{Expected: FFTok_colon, Got: tok}
 	}
 
-	tok, err = ffl.Scan(false)
+	_, err = ffl.scanValue()
+	return err
+}
 
-	if err != nil {
-		return false, err
+//预期类似   : 8
+func (ffl *FFLexer) ScanIntValue(bitSize int) (int64, error) {
+	if err := ffl.scanFieldValue(); err != nil {
+		return 0, err
+	}
+	return ffl.ValueInt(bitSize)
+}
+
+func (ffl *FFLexer) ScanUintValue(bitSize int) (uint64, error) {
+	if err := ffl.scanFieldValue(); err != nil {
+		return 0, err
 	}
+	return ffl.ValueUint(bitSize)
+}
 
-	if tok != FFTok_bool { //预期是 bool
-		return false, fmt.Errorf("ffjson: wanted bool value, but got token: %v", tok)
+func (ffl *FFLexer) ScanStringValue() (string, error) {
+	if err := ffl.scanFieldValue(); err != nil {
+		return "", err
 	}
+	return ffl.ValueString()
+}
 
-	if bytes.Equal(true_bytes, ffl.Output.Bytes()) {
-		return true, nil
-	} else if bytes.Equal(false_bytes, ffl.Output.Bytes()) {
-		return false, nil
-	} else {
-		return false, fmt.Errorf("ffjson: unknow bool value")
+func (ffl *FFLexer) ScanBoolValue(bitSize int) (bool, error) {
+	if err := ffl.scanFieldValue(); err != nil {
+		return false, err
 	}
+	return ffl.ValueBool()
 }
 
 func (ffl *FFLexer) ScanFloatValue() (float64, error) {
-	tok, err := ffl.Scan(false)
-	if err != nil {
+	if err := ffl.scanFieldValue(); err != nil {
 		return 0, err
 	}
+	return ffl.ValueFloat()
+}
 
-	if tok != FFTok_colon { //预期是冒号
-		return 0, fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", FFTok_colon, tok, ffl.Output.String())
+// ScanIntValueOrNull is ScanIntValue for optional fields: it also accepts
+// null, and then returns isNull true.
+func (ffl *FFLexer) ScanIntValueOrNull(bitSize int) (v int64, isNull bool, err error) {
+	if err = ffl.scanFieldValue(); err != nil {
+		return 0, false, err
+	}
+	if ffl.Token == FFTok_null {
+		return 0, true, nil
 	}
+	v, err = ffl.ValueInt(bitSize)
+	return v, false, err
+}
 
-	tok, err = ffl.Scan(false)
+// ScanUintValueOrNull is ScanUintValue which also accepts null.
+func (ffl *FFLexer) ScanUintValueOrNull(bitSize int) (v uint64, isNull bool, err error) {
+	if err = ffl.scanFieldValue(); err != nil {
+		return 0, false, err
+	}
+	if ffl.Token == FFTok_null {
+		return 0, true, nil
+	}
+	v, err = ffl.ValueUint(bitSize)
+	return v, false, err
+}
 
-	if err != nil {
-		return 0, err
+// ScanStringValueOrNull is ScanStringValue which also accepts null.
+func (ffl *FFLexer) ScanStringValueOrNull() (v string, isNull bool, err error) {
+	if err = ffl.scanFieldValue(); err != nil {
+		return "", false, err
+	}
+	if ffl.Token == FFTok_null {
+		return "", true, nil
 	}
+	v, err = ffl.ValueString()
+	return v, false, err
+}
 
-	if tok != FFTok_double { //预期是 float
-		return 0, fmt.Errorf("ffjson: wanted float value, but got token: %v", tok)
+// ScanBoolValueOrNull is ScanBoolValue which also accepts null.
+func (ffl *FFLexer) ScanBoolValueOrNull() (v bool, isNull bool, err error) {
+	if err = ffl.scanFieldValue(); err != nil {
+		return false, false, err
+	}
+	if ffl.Token == FFTok_null {
+		return false, true, nil
 	}
+	v, err = ffl.ValueBool()
+	return v, false, err
+}
 
-	return ParseFloat(ffl.Output.Bytes(), 64)
+// ScanFloatValueOrNull is ScanFloatValue which also accepts null.
+func (ffl *FFLexer) ScanFloatValueOrNull() (v float64, isNull bool, err error) {
+	if err = ffl.scanFieldValue(); err != nil {
+		return 0, false, err
+	}
+	if ffl.Token == FFTok_null {
+		return 0, true, nil
+	}
+	v, err = ffl.ValueFloat()
+	return v, false, err
 }
 
 func (ffl *FFLexer) ScanToValue() (FFTok, error) {
-	tok, err := ffl.Scan(false)
+	tok, err := ffl.scanValue()
 	if err != nil {
 		return tok, err
 	}
 
 	if tok != FFTok_colon {
-		return tok, fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", FFTok_colon, tok, ffl.Output.String())
+		return tok, &UnexpectedTokenError{Expected: FFTok_colon, Got: tok}
 	}
 
-	tok, err = ffl.Scan(false)
+	tok, err = ffl.scanValue()
 
 	if err != nil {
 		return tok, err
@@ -572,20 +1507,103 @@ func (ffl *FFLexer) ScanToValue() (FFTok, error) {
 		tok == FFTok_null {
 		return tok, nil
 	} else {
-		return tok, fmt.Errorf("ffjson: wanted value token, but got token: %v", tok)
+		return tok, &UnexpectedTokenError{Expected: FFTok_init, Got: tok}
 	}
 }
 
 func (ffl *FFLexer) Scan(captureall bool) (FFTok, error) {
+	if ffl.reader.push {
+		return ffl.scanPush(captureall)
+	}
+	return ffl.scan(captureall)
+}
+
+// scanPush rewinds the reader and the output to where they were before
+// the token, if it could not be completed with the input fed so far,
+// unless it is a string or a comment, which is left pending.
+func (ffl *FFLexer) scanPush(captureall bool) (FFTok, error) {
+	if ffl.pending.kind != pending_none {
+		return ffl.scanPending()
+	}
+
+	r := ffl.reader
+	start := r.Pos()
+	outlen := ffl.outputbuf.Len()
+
+	hold := r.hold
+	if hold < 0 || start < hold {
+		r.hold = start
+	}
+	tok, err := ffl.scan(captureall)
+	r.hold = hold
+
+	if err == ErrNeedMore && ffl.pending.kind != pending_none {
+		return FFTok_need_more, ErrNeedMore
+	}
+
+	if err == ErrNeedMore {
+		r.i = start - r.base
+		if captureall {
+			ffl.outputbuf.Truncate(outlen)
+		} else {
+			ffl.outputbuf.Reset()
+		}
+		return FFTok_need_more, ErrNeedMore
+	}
+
+	return tok, err
+}
+
+// scanPending goes on with the pending string or comment.
+func (ffl *FFLexer) scanPending() (FFTok, error) {
+	p := ffl.pending
+	ffl.pending = pushPending{}
+
+	var tok FFTok
+	var err error
+	switch p.kind {
+	case pending_string:
+		tok, err = ffl.lexStringRest(p.captureall)
+	case pending_single_quoted:
+		tok, err = ffl.lexSingleQuotedRest(p.captureall)
+	case pending_line_comment:
+		tok, err = ffl.lexLineComment(p.keep)
+	case pending_block_comment:
+		tok, err = ffl.lexBlockComment(p.keep, p.star)
+	}
+
+	if err == ErrNeedMore {
+		return FFTok_need_more, ErrNeedMore
+	}
+	if err != nil {
+		return FFTok_error, err
+	}
+
+	if err = ffl.count(tok); err != nil {
+		return FFTok_error, err
+	}
+	ffl.Token = tok
+	return tok, nil
+}
+
+func (ffl *FFLexer) scan(captureall bool) (FFTok, error) {
 	tok := FFTok_error
 	if !captureall {
 		ffl.outputbuf.Reset()
 	}
 	ffl.Token = FFTok_init
+	ffl.hasView = false
+	ffl.hasNum = false
 
 	var c byte
 	var err error
 
+	if ffl.limited {
+		if err = ffl.reader.inputLimit(); err != nil {
+			return FFTok_error, err
+		}
+	}
+
 	for {
 		if captureall {
 			c, err = ffl.reader.ReadByte()
@@ -601,6 +1619,16 @@ func (ffl *FFLexer) Scan(captureall bool) (FFTok, error) {
 			}
 		}
 
+		ffl.tokStart = ffl.reader.Pos() - 1
+
+		if ffl.syntax == FFSyntax_json5 && json5Start(c) {
+			tok, err = ffl.lexJSON5(c, captureall)
+			if err != nil {
+				return FFTok_error, err
+			}
+			goto lexed
+		}
+
 		switch c {
 		case '{':
 			tok = FFTok_left_bracket
@@ -638,7 +1666,15 @@ func (ffl *FFLexer) Scan(captureall bool) (FFTok, error) {
 				ffl.outputbuf.WriteByte(':')
 			}
 			goto lexed
-		case '\t', '\n', '\v', '\f', '\r', ' ':
+		case '\v', '\f':
+			if ffl.syntax == FFSyntax_strict {
+				return FFTok_error, NewFFError(FFErr_invalid_char)
+			}
+			if captureall {
+				ffl.outputbuf.WriteByte(c)
+			}
+			break
+		case '\t', '\n', '\r', ' ':
 			if captureall {
 				ffl.outputbuf.WriteByte(c)
 			}
@@ -679,7 +1715,10 @@ func (ffl *FFLexer) Scan(captureall bool) (FFTok, error) {
 			}
 			goto lexed
 		case '/':
-			tok, err = ffl.lexComment()
+			if ffl.syntax == FFSyntax_strict {
+				return FFTok_error, NewFFError(FFErr_unallowed_comment)
+			}
+			tok, err = ffl.lexComment(ffl.keepComments && !captureall)
 			if err != nil {
 				return FFTok_error, err
 			}
@@ -690,10 +1729,54 @@ func (ffl *FFLexer) Scan(captureall bool) (FFTok, error) {
 	}
 
 lexed:
+	if ffl.limited {
+		if err = ffl.count(tok); err != nil {
+			return FFTok_error, err
+		}
+	} else if tok == FFTok_left_bracket || tok == FFTok_left_brace {
+		ffl.depth++
+	} else if (tok == FFTok_right_bracket || tok == FFTok_right_brace) && ffl.depth > 0 {
+		ffl.depth--
+	}
 	ffl.Token = tok
 	return tok, nil
 }
 
+// count checks the token and the depth limits for a lexed token. Without
+// limits, only the depth is kept, for ObjectEach and ArrayEach.
+func (ffl *FFLexer) count(tok FFTok) error {
+	if !ffl.limited {
+		switch tok {
+		case FFTok_left_bracket, FFTok_left_brace:
+			ffl.depth++
+		case FFTok_right_bracket, FFTok_right_brace:
+			if ffl.depth > 0 {
+				ffl.depth--
+			}
+		}
+		return nil
+	}
+
+	ffl.tokens++
+	if max := ffl.limits.MaxTokens; max > 0 && ffl.tokens > max {
+		return limitError("token", max)
+	}
+
+	switch tok {
+	case FFTok_left_bracket, FFTok_left_brace:
+		ffl.depth++
+		if max := ffl.limits.MaxDepth; max > 0 && ffl.depth > max {
+			return limitError("depth", max)
+		}
+	case FFTok_right_bracket, FFTok_right_brace:
+		if ffl.depth > 0 {
+			ffl.depth--
+		}
+	}
+
+	return nil
+}
+
 func (ffl *FFLexer) captureField(start FFTok) ([]byte, error) {
 	switch start {
 	case FFTok_left_brace,
@@ -713,13 +1796,14 @@ func (ffl *FFLexer) captureField(start FFTok) ([]byte, error) {
 		scanloop:
 			for {
 				tok, err := ffl.Scan(true)
+				if tok == FFTok_eof {
+					return nil, newEOFError()
+				}
 				if err != nil {
 					return nil, err
 				}
 				//fmt.Printf("capture-token: %v end: %v depth: %v\n", tok, end, depth)
 				switch tok {
-				case FFTok_eof:
-					return nil, errors.New("ffjson: unexpected EOF")
 				case end:
 					depth--
 					if depth == 0 {
@@ -749,7 +1833,7 @@ func (ffl *FFLexer) captureField(start FFTok) ([]byte, error) {
 		return ffl.buf.Bytes(), nil
 
 	default:
-		return nil, fmt.Errorf("ffjson: invalid capture type: %v", start)
+		return nil, &UnexpectedTokenError{Expected: FFTok_init, Got: start}
 	}
 	panic("not reached")
 }
@@ -761,11 +1845,45 @@ func (ffl *FFLexer) CaptureField(start FFTok) ([]byte, error) {
 	return ffl.captureField(start)
 }
 
+// CaptureRaw is CaptureField which returns the value exactly as it is in
+// the input, from the first byte of start, the token scanned last, to the
+// end of the value, including escapes, whitespace and comments. For a
+// lexer on a []byte, it is a slice of that input and nothing is copied.
+// For one reading from an io.Reader or in push mode, it is a slice of its
+// window and only valid until the next Scan.
+func (ffl *FFLexer) CaptureRaw(start FFTok) ([]byte, error) {
+	r := ffl.reader
+	from := ffl.tokStart
+
+	// keep the value in the window while skipping it.
+	hold := r.hold
+	if hold < 0 || from < hold {
+		r.hold = from
+	}
+	err := ffl.SkipField(start)
+	r.hold = hold
+
+	if err != nil {
+		return nil, err
+	}
+	return r.s[from-r.base : r.i], nil
+}
+
+// SkipField skips the value start, the token scanned last, begins. An
+// object or array is skipped up to its closing bracket, which is then the
+// Token. Unless the syntax is strict, the lexer is in push mode, or a
+// token, string or number limit is set, its contents are not scanned: only
+// the brackets, strings and comments are followed, so invalid bytes in it
+// such as [1, @@@] are skipped without an error.
 func (ffl *FFLexer) SkipField(start FFTok) error {
 	switch start {
 	case FFTok_left_brace, //'['
 		FFTok_left_bracket: //'{'
 		{
+			if ffl.fastSkip() {
+				return ffl.skipContainer(start)
+			}
+
 			end := FFTok_right_brace
 			if start == FFTok_left_bracket {
 				end = FFTok_right_bracket
@@ -775,13 +1893,14 @@ func (ffl *FFLexer) SkipField(start FFTok) error {
 		scanloop:
 			for {
 				tok, err := ffl.Scan(false)
+				if tok == FFTok_eof {
+					return newEOFError()
+				}
 				if err != nil {
 					return err
 				}
 
 				switch tok {
-				case FFTok_eof:
-					return fmt.Errorf("ffjson: unexpected EOF")
 				case end:
 					depth--
 					if depth == 0 {
@@ -802,7 +1921,7 @@ func (ffl *FFLexer) SkipField(start FFTok) error {
 
 		return nil
 	default:
-		return fmt.Errorf("ffjson: invalid capture type: %v", start)
+		return &UnexpectedTokenError{Expected: FFTok_init, Got: start}
 	}
 
 	panic("not reached")
@@ -820,6 +1939,10 @@ func (state FFParseState) String() string {
 		return "want_value"
 	case FFParse_after_value:
 		return "after_value"
+	case FFParse_array_start:
+		return "array:start"
+	case FFParse_end:
+		return "end"
 	}
 
 	panic(fmt.Sprintf("unknown parse state: %d", int(state)))
@@ -857,6 +1980,10 @@ func (tok FFTok) String() string {
 		return "tok:string"
 	case FFTok_comment:
 		return "comment"
+	case FFTok_need_more:
+		return "tok:need_more"
+	case FFTok_key:
+		return "tok:key"
 	}
 
 	panic(fmt.Sprintf("unknown token: %d", int(tok)))
@@ -0,0 +1,202 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"bytes"
+	"math/big"
+)
+
+// FFCoerce selects the conversions the Value helpers, and so the
+// Scan*Value helpers, make between numbers of the wrong kind. Any of them
+// fails with a *NumError wrapping ErrPrecision rather than change a value.
+type FFCoerce uint
+
+const (
+	// numbers inside a string, like encoding/json's ,string option.
+	FFCoerce_quoted FFCoerce = 1 << iota
+	// integers for floats, as long as the float holds them exactly.
+	FFCoerce_int_to_float
+	// numbers with a fraction or an exponent for integers, as long as
+	// they are integral, like 3.0 or 1e3.
+	FFCoerce_float_to_int
+
+	FFCoerce_none FFCoerce = 0
+	FFCoerce_all  FFCoerce = FFCoerce_quoted | FFCoerce_int_to_float | FFCoerce_float_to_int
+)
+
+// SetCoerce selects the conversions the Value helpers make, it is kept
+// over Reset.
+func (ffl *FFLexer) SetCoerce(coerce FFCoerce) {
+	ffl.coerce = coerce
+}
+
+// numberTok returns the token the number last scanned is, after looking
+// into a string with FFCoerce_quoted.
+func (ffl *FFLexer) numberTok() FFTok {
+	if ffl.Token == FFTok_string && ffl.coerce&FFCoerce_quoted != 0 {
+		if tok := numberTok(ffl.View()); tok != FFTok_error {
+			return tok
+		}
+	}
+	return ffl.Token
+}
+
+// numberTok returns FFTok_integer or FFTok_double if b is a JSON number,
+// FFTok_error otherwise.
+func numberTok(b []byte) FFTok {
+	tok := FFTok_integer
+	i := 0
+	digits := func() int {
+		n := 0
+		for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+			i++
+			n++
+		}
+		return n
+	}
+
+	if i < len(b) && b[i] == '-' {
+		i++
+	}
+
+	if i < len(b) && b[i] == '0' {
+		i++
+	} else if digits() == 0 {
+		return FFTok_error
+	}
+
+	if i < len(b) && b[i] == '.' {
+		i++
+		if digits() == 0 {
+			return FFTok_error
+		}
+		tok = FFTok_double
+	}
+
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		i++
+		if i < len(b) && (b[i] == '+' || b[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return FFTok_error
+		}
+		tok = FFTok_double
+	}
+
+	if i != len(b) {
+		return FFTok_error
+	}
+	return tok
+}
+
+// integralNumber appends the digits of num, a JSON number with a fraction
+// or an exponent, to dst, if it is integral. Numbers of more than 20
+// digits, which no integer type holds, are an ErrRange.
+func integralNumber(dst, num []byte, fn string) ([]byte, error) {
+	mant := num
+	exp := int64(0)
+	if i := bytes.IndexAny(num, "eE"); i >= 0 {
+		mant = num[:i]
+		var err error
+		if exp, err = ParseInt(num[i+1:], 10, 32); err != nil {
+			return nil, &NumError{Func: fn, Num: string(num), Err: ErrRange}
+		}
+	}
+
+	if len(mant) > 0 && mant[0] == '-' {
+		dst = append(dst, '-')
+		mant = mant[1:]
+	}
+
+	intPart, frac := mant, mant[len(mant):]
+	if i := bytes.IndexByte(mant, '.'); i >= 0 {
+		intPart, frac = mant[:i], mant[i+1:]
+	}
+
+	n := int64(len(intPart) + len(frac))
+	digit := func(k int64) byte {
+		if k >= n {
+			return '0'
+		}
+		if k < int64(len(intPart)) {
+			return intPart[k]
+		}
+		return frac[k-int64(len(intPart))]
+	}
+
+	// the decimal point is after digit point.
+	point := int64(len(intPart)) + exp
+	for k := point; k < n; k++ {
+		if k < 0 {
+			k = 0
+		}
+		if digit(k) != '0' {
+			return nil, &NumError{Func: fn, Num: string(num), Err: ErrPrecision}
+		}
+	}
+
+	k := int64(0)
+	for k < point && digit(k) == '0' && k < n {
+		k++
+	}
+	if point-k > 20 {
+		return nil, &NumError{Func: fn, Num: string(num), Err: ErrRange}
+	}
+	if k >= point {
+		return append(dst, '0'), nil
+	}
+	for ; k < point; k++ {
+		dst = append(dst, digit(k))
+	}
+	return dst, nil
+}
+
+// exactFloat returns the integer num as a float of bitSize, if it holds
+// it exactly.
+func exactFloat(num []byte, bitSize int) (float64, error) {
+	// every integer up to 2**mantissa bits is exact.
+	max := int64(1) << 53
+	if bitSize == 32 {
+		max = 1 << 24
+	}
+	if n, err := ParseInt(num, 10, 64); err == nil && n >= -max && n <= max {
+		return float64(n), nil
+	}
+
+	bi, ok := new(big.Int).SetString(string(num), 10)
+	if !ok {
+		return 0, &NumError{Func: "ParseFloat", Num: string(num), Err: ErrSyntax}
+	}
+
+	bf := new(big.Float).SetInt(bi)
+	var f float64
+	var acc big.Accuracy
+	if bitSize == 32 {
+		var f32 float32
+		f32, acc = bf.Float32()
+		f = float64(f32)
+	} else {
+		f, acc = bf.Float64()
+	}
+
+	if acc != big.Exact {
+		return 0, &NumError{Func: "ParseFloat", Num: string(num), Err: ErrPrecision}
+	}
+	return f, nil
+}
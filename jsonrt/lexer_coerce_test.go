@@ -0,0 +1,122 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"errors"
+	"testing"
+)
+
+func scanOne(t *testing.T, input string, coerce FFCoerce) *FFLexer {
+	ffl := NewFFLexer([]byte(input))
+	ffl.SetCoerce(coerce)
+	if _, err := ffl.Scan(false); err != nil {
+		t.Fatalf("unexpected error for %s: %v", input, err)
+	}
+	return ffl
+}
+
+func TestCoerceInt(t *testing.T) {
+	tests := []struct {
+		input  string
+		coerce FFCoerce
+		v      int64
+		err    error
+	}{
+		{`12`, FFCoerce_none, 12, nil},
+		{`"12"`, FFCoerce_none, 0, FFErr_unexpected_token_type},
+		{`"12"`, FFCoerce_quoted, 12, nil},
+		{`"-9223372036854775808"`, FFCoerce_quoted, -9223372036854775808, nil},
+		{`"12 "`, FFCoerce_quoted, 0, FFErr_unexpected_token_type},
+		{`"1.0"`, FFCoerce_quoted, 0, FFErr_unexpected_token_type},
+		{`"1.0"`, FFCoerce_all, 1, nil},
+		{`3.0`, FFCoerce_none, 0, FFErr_unexpected_token_type},
+		{`3.0`, FFCoerce_float_to_int, 3, nil},
+		{`-3.500e1`, FFCoerce_float_to_int, -35, nil},
+		{`1e3`, FFCoerce_float_to_int, 1000, nil},
+		{`12500e-2`, FFCoerce_float_to_int, 125, nil},
+		{`0.0e-2000000000`, FFCoerce_float_to_int, 0, nil},
+		{`3.5`, FFCoerce_float_to_int, 0, ErrPrecision},
+		{`1e-3`, FFCoerce_float_to_int, 0, ErrPrecision},
+		{`9223372036854775807.0`, FFCoerce_float_to_int, 9223372036854775807, nil},
+		{`9223372036854775808.0`, FFCoerce_float_to_int, 0, ErrRange},
+		{`1e30`, FFCoerce_float_to_int, 0, ErrRange},
+		{`1e2000000000`, FFCoerce_float_to_int, 0, ErrRange},
+	}
+
+	for _, tt := range tests {
+		v, err := scanOne(t, tt.input, tt.coerce).ValueInt(64)
+		if tt.err == nil && err != nil || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Fatalf("unexpected error for %s: %v", tt.input, err)
+		}
+		if err == nil && v != tt.v {
+			t.Fatalf("unexpected value for %s: %d", tt.input, v)
+		}
+	}
+}
+
+func TestCoerceUint(t *testing.T) {
+	v, err := scanOne(t, `"18446744073709551615"`, FFCoerce_quoted).ValueUint(64)
+	if err != nil || v != 18446744073709551615 {
+		t.Fatalf("unexpected value: %d %v", v, err)
+	}
+
+	v, err = scanOne(t, `2.56e2`, FFCoerce_float_to_int).ValueUint(8)
+	if !errors.Is(err, ErrRange) {
+		t.Fatalf("expected a range error, got %d %v", v, err)
+	}
+}
+
+func TestCoerceFloat(t *testing.T) {
+	tests := []struct {
+		input  string
+		coerce FFCoerce
+		v      float64
+		err    error
+	}{
+		{`1.5`, FFCoerce_none, 1.5, nil},
+		{`3`, FFCoerce_none, 0, FFErr_unexpected_token_type},
+		{`3`, FFCoerce_int_to_float, 3, nil},
+		{`"-2.5e-1"`, FFCoerce_quoted, -0.25, nil},
+		{`"7"`, FFCoerce_quoted, 0, FFErr_unexpected_token_type},
+		{`"7"`, FFCoerce_all, 7, nil},
+		{`9007199254740993`, FFCoerce_int_to_float, 0, ErrPrecision},
+		{`-9007199254740992`, FFCoerce_int_to_float, -9007199254740992, nil},
+		{`1152921504606846976`, FFCoerce_int_to_float, 1152921504606846976, nil},
+		{`100000000000000000000000`, FFCoerce_int_to_float, 0, ErrPrecision},
+	}
+
+	for _, tt := range tests {
+		v, err := scanOne(t, tt.input, tt.coerce).ValueFloat()
+		if tt.err == nil && err != nil || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Fatalf("unexpected error for %s: %v", tt.input, err)
+		}
+		if err == nil && v != tt.v {
+			t.Fatalf("unexpected value for %s: %v", tt.input, v)
+		}
+	}
+}
+
+func TestCoerceScanValue(t *testing.T) {
+	ffl := NewFFLexer([]byte(`: "42"`))
+	ffl.SetCoerce(FFCoerce_quoted)
+
+	v, err := ffl.ScanIntValue(64)
+	if err != nil || v != 42 {
+		t.Fatalf("unexpected value: %d %v", v, err)
+	}
+}
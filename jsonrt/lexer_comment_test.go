@@ -0,0 +1,71 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jsonrt
+
+import (
+	"testing"
+)
+
+func TestKeepComments(t *testing.T) {
+	input := "{\n  // the name\r\n  \"name\": \"x\", /** doc * with stars **/\n  \"n\": 1 // last"
+	ffl := NewFFLexer([]byte(input))
+	ffl.SetKeepComments(true)
+
+	want := []struct {
+		tok    FFTok
+		out    string
+		line   int
+		column int
+	}{
+		{FFTok_left_bracket, "", 1, 1},
+		{FFTok_comment, "// the name", 2, 3},
+		{FFTok_string, "name", 3, 3},
+		{FFTok_colon, "", 3, 9},
+		{FFTok_string, "x", 3, 11},
+		{FFTok_comma, "", 3, 14},
+		{FFTok_comment, "/** doc * with stars **/", 3, 16},
+		{FFTok_string, "n", 4, 3},
+		{FFTok_colon, "", 4, 6},
+		{FFTok_integer, "1", 4, 8},
+		{FFTok_comment, "// last", 4, 10},
+	}
+
+	for i, w := range want {
+		tok, err := ffl.Scan(false)
+		if err != nil {
+			t.Fatalf("token %d: Scan failed: %v", i, err)
+		}
+		_, line, column := ffl.TokenPos()
+		if tok != w.tok || ffl.Output.String() != w.out || line != w.line || column != w.column {
+			t.Fatalf("token %d: expected %v %q at %d:%d, got %v %q at %d:%d",
+				i, w.tok, w.out, w.line, w.column, tok, ffl.Output.String(), line, column)
+		}
+	}
+
+	if tok, _ := ffl.Scan(false); tok != FFTok_eof {
+		t.Fatalf("expected eof, got %v", tok)
+	}
+}
+
+func TestCommentsNotKept(t *testing.T) {
+	ffl := NewFFLexer([]byte(`/* a */ 1`))
+	tok, err := ffl.Scan(false)
+	if err != nil || tok != FFTok_comment || ffl.Output.Len() != 0 {
+		t.Fatalf("expected bare comment token, got %v %q %v", tok, ffl.Output.String(), err)
+	}
+}
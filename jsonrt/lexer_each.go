@@ -0,0 +1,278 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"bytes"
+)
+
+// ObjectEach calls fn for each field of an object: with the key, and the
+// token which starts the value, which is in Token and Output when fn is
+// called. fn may read the value with the Value helpers, or ObjectEach and
+// ArrayEach for a nested object or array; whatever fn leaves of a nested
+// value, all of it or the rest after a partial read, is skipped. key is
+// only valid until fn returns.
+//
+// If Token is '{' the object is taken to start there, otherwise it is
+// scanned first. After ObjectEach returns nil, Token is the closing '}'.
+func (ffl *FFLexer) ObjectEach(fn func(key []byte, tok FFTok) error) error {
+	return ffl.objectEach(nil, fn)
+}
+
+// objectEach is ObjectEach, with fast tried first for each field like in
+// each.
+func (ffl *FFLexer) objectEach(fast func() (bool, error), fn func(key []byte, tok FFTok) error) error {
+	var key, keybuf []byte
+
+	return ffl.each(FFTok_left_bracket, FFTok_right_bracket, true, fast, func() error {
+		tok := ffl.Token
+		if tok != FFTok_string {
+			return &UnexpectedTokenError{Expected: FFTok_string, Got: tok}
+		}
+		if ffl.hasView && ffl.reader.inputView() {
+			key = ffl.view
+		} else {
+			key = append(keybuf[:0], ffl.View()...)
+			keybuf = key
+		}
+
+		tok, err := ffl.scanValue()
+		if err != nil {
+			return err
+		}
+		if tok != FFTok_colon {
+			return &UnexpectedTokenError{Expected: FFTok_colon, Got: tok}
+		}
+
+		tok, err = ffl.scanValue()
+		if err != nil {
+			return err
+		}
+
+		return ffl.eachValue(tok, func() error { return fn(key, tok) })
+	})
+}
+
+// ArrayEach calls fn for each element of an array, with its index and the
+// token which starts it, like ObjectEach does for the fields of an object.
+func (ffl *FFLexer) ArrayEach(fn func(i int, tok FFTok) error) error {
+	i := 0
+
+	return ffl.each(FFTok_left_brace, FFTok_right_brace, false, nil, func() error {
+		tok := ffl.Token
+		err := ffl.eachValue(tok, func() error { return fn(i, tok) })
+		i++
+		return err
+	})
+}
+
+// each runs the loop of ObjectEach and ArrayEach over the commas of an
+// object or array, elem is called with Token at the start of each element.
+// The first token of an element is scanned with ScanView if view is set.
+// If fast is not nil, it is called before an element is scanned, and may
+// handle the whole element itself, returning true.
+func (ffl *FFLexer) each(open, close FFTok, view bool, fast func() (bool, error), elem func() error) error {
+	if ffl.Token != open {
+		tok, err := ffl.scanValue()
+		if err != nil {
+			return err
+		}
+		if tok != open {
+			return &UnexpectedTokenError{Expected: open, Got: tok}
+		}
+	}
+
+	// an empty container, or, in JSON5, a trailing comma.
+	first := true
+	for {
+		done := false
+		if fast != nil {
+			var err error
+			if done, err = fast(); err != nil {
+				return err
+			}
+		}
+
+		if !done {
+			ffl.noCopy = view
+			tok, err := ffl.scanValue()
+			ffl.noCopy = false
+			if err != nil {
+				return err
+			}
+
+			if tok == close && (first || ffl.syntax == FFSyntax_json5) {
+				return nil
+			}
+
+			if err = elem(); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		tok, err := ffl.scanValue()
+		if err != nil {
+			return err
+		}
+
+		switch tok {
+		case FFTok_comma:
+		case close:
+			return nil
+		default:
+			return &UnexpectedTokenError{Expected: FFTok_comma, Got: tok}
+		}
+	}
+}
+
+// eachValue calls fn for the value starting with tok, and skips what is
+// left of the value if it is an object or array fn did not read to its end.
+func (ffl *FFLexer) eachValue(tok FFTok, fn func() error) error {
+	switch tok {
+	case FFTok_left_bracket, FFTok_left_brace:
+		pos := ffl.reader.Pos()
+		depth := ffl.depth
+		if err := fn(); err != nil {
+			return err
+		}
+		if ffl.reader.Pos() == pos {
+			return ffl.SkipField(tok)
+		}
+
+		// fn stopped inside the value, skip up to its closing bracket.
+		for ffl.depth >= depth {
+			tok, err := ffl.scanValue()
+			if err != nil {
+				return err
+			}
+			if tok == FFTok_left_bracket || tok == FFTok_left_brace {
+				if err = ffl.SkipField(tok); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case FFTok_string, FFTok_integer, FFTok_double, FFTok_bool, FFTok_null:
+		return fn()
+	}
+
+	return &UnexpectedTokenError{Expected: FFTok_init, Got: tok}
+}
+
+// ValueInt returns the integer the last Scan returned, converted as
+// selected by SetCoerce.
+func (ffl *FFLexer) ValueInt(bitSize int) (int64, error) {
+	switch ffl.numberTok() {
+	case FFTok_integer:
+		if ffl.hasNum {
+			if v, ok := ffl.num.int(bitSize); ok {
+				return v, nil
+			}
+		}
+		return ParseInt(ffl.View(), 10, bitSize)
+	case FFTok_double:
+		if ffl.coerce&FFCoerce_float_to_int != 0 {
+			var buf [24]byte
+			num, err := integralNumber(buf[:0], ffl.View(), "ParseInt")
+			if err != nil {
+				return 0, err
+			}
+			return ParseInt(num, 10, bitSize)
+		}
+	}
+	return 0, &UnexpectedTokenError{Expected: FFTok_integer, Got: ffl.Token}
+}
+
+// ValueUint returns the unsigned integer the last Scan returned, like
+// ValueInt.
+func (ffl *FFLexer) ValueUint(bitSize int) (uint64, error) {
+	switch ffl.numberTok() {
+	case FFTok_integer:
+		if ffl.hasNum {
+			if v, ok := ffl.num.uint(bitSize); ok {
+				return v, nil
+			}
+		}
+		return ParseUint(ffl.View(), 10, bitSize)
+	case FFTok_double:
+		if ffl.coerce&FFCoerce_float_to_int != 0 {
+			var buf [24]byte
+			num, err := integralNumber(buf[:0], ffl.View(), "ParseUint")
+			if err != nil {
+				return 0, err
+			}
+			return ParseUint(num, 10, bitSize)
+		}
+	}
+	return 0, &UnexpectedTokenError{Expected: FFTok_integer, Got: ffl.Token}
+}
+
+// ValueString returns the string the last Scan returned.
+func (ffl *FFLexer) ValueString() (string, error) {
+	if ffl.Token != FFTok_string {
+		return "", &UnexpectedTokenError{Expected: FFTok_string, Got: ffl.Token}
+	}
+	if ffl.intern != nil {
+		return ffl.intern.String(ffl.View()), nil
+	}
+	return string(ffl.View()), nil
+}
+
+// ValueBool returns the bool the last Scan returned.
+func (ffl *FFLexer) ValueBool() (bool, error) {
+	if ffl.Token != FFTok_bool {
+		return false, &UnexpectedTokenError{Expected: FFTok_bool, Got: ffl.Token}
+	}
+
+	if bytes.Equal(true_bytes, ffl.View()) {
+		return true, nil
+	} else if bytes.Equal(false_bytes, ffl.View()) {
+		return false, nil
+	}
+	return false, NewFFError(FFErr_invalid_string)
+}
+
+// ValueFloat returns the floating point number the last Scan returned,
+// converted as selected by SetCoerce.
+func (ffl *FFLexer) ValueFloat() (float64, error) {
+	return ffl.valueFloat(64)
+}
+
+// ValueFloat32 is ValueFloat for a float32, the number is rounded to
+// float32 directly, not through a float64.
+func (ffl *FFLexer) ValueFloat32() (float32, error) {
+	f, err := ffl.valueFloat(32)
+	return float32(f), err
+}
+
+func (ffl *FFLexer) valueFloat(bitSize int) (float64, error) {
+	switch ffl.numberTok() {
+	case FFTok_double:
+		if ffl.hasNum {
+			if v, ok := ffl.num.float(bitSize); ok {
+				return v, nil
+			}
+		}
+		return ParseFloat(ffl.View(), bitSize)
+	case FFTok_integer:
+		if ffl.coerce&FFCoerce_int_to_float != 0 {
+			return exactFloat(ffl.View(), bitSize)
+		}
+	}
+	return 0, &UnexpectedTokenError{Expected: FFTok_double, Got: ffl.Token}
+}
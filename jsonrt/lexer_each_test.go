@@ -0,0 +1,178 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestObjectEach(t *testing.T) {
+	ffl := NewFFLexer([]byte(`{"id": 7, "skip": {"a": [1, {}]}, "tags": ["x", "y"], "ok": true}`))
+
+	var id int64
+	var tags []string
+	var keys []string
+	var ok bool
+
+	err := ffl.ObjectEach(func(key []byte, tok FFTok) error {
+		keys = append(keys, string(key))
+
+		var err error
+		switch string(key) {
+		case "id":
+			id, err = ffl.ValueInt(64)
+		case "tags":
+			err = ffl.ArrayEach(func(i int, tok FFTok) error {
+				s, err := ffl.ValueString()
+				tags = append(tags, s)
+				return err
+			})
+		case "ok":
+			ok, err = ffl.ValueBool()
+		}
+		return err
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != 7 || !ok || !reflect.DeepEqual(tags, []string{"x", "y"}) {
+		t.Fatalf("unexpected values: %d %v %v", id, ok, tags)
+	}
+	if !reflect.DeepEqual(keys, []string{"id", "skip", "tags", "ok"}) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	if err = ffl.ExpectEOF(); err != nil {
+		t.Fatalf("unexpected trailing data: %v", err)
+	}
+}
+
+func TestArrayEach(t *testing.T) {
+	tests := []struct {
+		input  string
+		syntax FFSyntax
+		n      int
+		err    error
+	}{
+		{`[]`, FFSyntax_default, 0, nil},
+		{`[1, [2, 3], {"a": 4}, null]`, FFSyntax_default, 4, nil},
+		{`[1, 2,]`, FFSyntax_json5, 2, nil},
+		{`[1, 2,]`, FFSyntax_default, 2, FFErr_unexpected_token_type},
+		{`[1 2]`, FFSyntax_default, 1, FFErr_unexpected_token_type},
+		{`[1, 2`, FFSyntax_default, 2, ErrUnexpectedEOF},
+		{`{}`, FFSyntax_default, 0, FFErr_unexpected_token_type},
+	}
+
+	for _, tt := range tests {
+		ffl := NewFFLexer([]byte(tt.input))
+		ffl.SetSyntax(tt.syntax)
+
+		n := 0
+		err := ffl.ArrayEach(func(i int, tok FFTok) error {
+			if i != n {
+				t.Fatalf("unexpected index %d, wanted %d", i, n)
+			}
+			n++
+			return nil
+		})
+
+		if tt.err == nil && err != nil || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Fatalf("unexpected error for %s: %v", tt.input, err)
+		}
+		if n != tt.n {
+			t.Fatalf("unexpected count for %s: %d", tt.input, n)
+		}
+	}
+}
+
+func TestObjectEachError(t *testing.T) {
+	stop := errors.New("stop")
+	ffl := NewFFLexer([]byte(`{"a": 1, "b": 2}`))
+
+	err := ffl.ObjectEach(func(key []byte, tok FFTok) error {
+		return stop
+	})
+	if err != stop {
+		t.Fatalf("expected the error of the callback, got %v", err)
+	}
+
+	ffl = NewFFLexer([]byte(`{"a" 1}`))
+	err = ffl.ObjectEach(func(key []byte, tok FFTok) error { return nil })
+
+	var ute *UnexpectedTokenError
+	if !errors.As(err, &ute) || ute.Expected != FFTok_colon {
+		t.Fatalf("expected a missing colon, got %v", err)
+	}
+}
+
+func TestObjectEachPartial(t *testing.T) {
+	input := `{"a": {"x": 1, "y": {"z": [2]}}, "b": [3, [4, 5], 6], "c": {"d": [7, 8]}, "e": 9}`
+
+	// each callback reads a different part of its value and returns.
+	partial := map[string]func(ffl *FFLexer) error{
+		"a": func(ffl *FFLexer) error {
+			_, err := ffl.Scan(false) // "x"
+			return err
+		},
+		"b": func(ffl *FFLexer) error {
+			return ffl.ArrayEach(func(i int, tok FFTok) error {
+				if i == 1 {
+					return stopArray
+				}
+				return nil
+			})
+		},
+		"c": func(ffl *FFLexer) error {
+			for i := 0; i < 4; i++ { // "d" : [ 7
+				if _, err := ffl.Scan(false); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	var keys []string
+	var e int64
+	ffl := NewFFLexer([]byte(input))
+	err := ffl.ObjectEach(func(key []byte, tok FFTok) error {
+		keys = append(keys, string(key))
+		if fn, ok := partial[string(key)]; ok {
+			if err := fn(ffl); err != nil && err != stopArray {
+				return err
+			}
+			return nil
+		}
+		var err error
+		e, err = ffl.ValueInt(64)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(keys, ",") != "a,b,c,e" || e != 9 {
+		t.Fatalf("unexpected fields: %v %d", keys, e)
+	}
+	if ffl.Token != FFTok_right_bracket || ffl.depth != 0 {
+		t.Fatalf("expected the lexer after the object, got %v at depth %d", ffl.Token, ffl.depth)
+	}
+}
+
+var stopArray = errors.New("stop")
@@ -0,0 +1,204 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jsonrt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Offset returns the offset in the input where lexing stopped.
+func (le *LexerError) Offset() int {
+	return le.offset
+}
+
+// Line returns the line of Offset, counted from 1.
+func (le *LexerError) Line() int {
+	return le.line
+}
+
+// Column returns the column of the last byte read before Offset, counted
+// from 1 in bytes.
+func (le *LexerError) Column() int {
+	return le.char
+}
+
+// Path returns the JSON path of the value being decoded, like
+// $.items[3].price, or "" if it is not known because the input was
+// streamed.
+func (le *LexerError) Path() string {
+	return le.path
+}
+
+// Snippet returns the input line around the token which failed, and a
+// second line with a ^ under the start of that token.
+func (le *LexerError) Snippet() string {
+	if le.snippet == "" {
+		return ""
+	}
+	return le.snippet + "\n" + strings.Repeat(" ", le.caret) + "^"
+}
+
+func (le *LexerError) Unwrap() error {
+	return le.err
+}
+
+// UnexpectedTokenError is returned when a value or a token other than the
+// one expected is scanned. Expected is FFTok_init when any value would
+// do. It matches FFErr_unexpected_token_type with errors.Is.
+type UnexpectedTokenError struct {
+	Expected FFTok
+	Got      FFTok
+}
+
+func (e *UnexpectedTokenError) Error() string {
+	switch e.Expected {
+	case FFTok_init:
+		return fmt.Sprintf("ffjson: wanted value token, but got token: %v", e.Got)
+	case FFTok_integer:
+		return fmt.Sprintf("ffjson: wanted int value, but got token: %v", e.Got)
+	case FFTok_string:
+		return fmt.Sprintf("ffjson: wanted string value, but got token: %v", e.Got)
+	case FFTok_bool:
+		return fmt.Sprintf("ffjson: wanted bool value, but got token: %v", e.Got)
+	case FFTok_double:
+		return fmt.Sprintf("ffjson: wanted float value, but got token: %v", e.Got)
+	}
+	return fmt.Sprintf("ffjson: wanted token: %v, but got token: %v", e.Expected, e.Got)
+}
+
+func (e *UnexpectedTokenError) Is(target error) bool {
+	return target == FFErr_unexpected_token_type
+}
+
+type pathElem struct {
+	object bool
+	hasKey bool
+	key    []byte
+	index  int
+}
+
+// jsonPathAt returns the JSON path of the value data ends in, by scanning
+// it again from the start. It is slow and should only be used in
+// error-paths.
+func jsonPathAt(data []byte, syntax FFSyntax) string {
+	ffl := NewFFLexer(data)
+	ffl.SetSyntax(syntax)
+
+	var stack []pathElem
+	wantKey := false
+
+	for {
+		tok, err := ffl.Scan(false)
+		if err != nil {
+			break
+		}
+
+		switch tok {
+		case FFTok_left_bracket:
+			stack = append(stack, pathElem{object: true})
+			wantKey = true
+		case FFTok_left_brace:
+			stack = append(stack, pathElem{})
+			wantKey = false
+		case FFTok_right_bracket, FFTok_right_brace:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			wantKey = false
+		case FFTok_comma:
+			if len(stack) > 0 {
+				top := &stack[len(stack)-1]
+				if top.object {
+					top.hasKey = false
+					wantKey = true
+				} else {
+					top.index++
+				}
+			}
+		case FFTok_string:
+			if wantKey && len(stack) > 0 {
+				top := &stack[len(stack)-1]
+				top.key = append(top.key[:0], ffl.Output.Bytes()...)
+				top.hasKey = true
+				wantKey = false
+			}
+		}
+	}
+
+	path := []byte{'$'}
+	for _, e := range stack {
+		if !e.object {
+			path = append(path, '[')
+			path = strconv.AppendInt(path, int64(e.index), 10)
+			path = append(path, ']')
+		} else if e.hasKey {
+			if isPathName(e.key) {
+				path = append(path, '.')
+				path = append(path, e.key...)
+			} else {
+				path = append(path, '[')
+				path = strconv.AppendQuote(path, string(e.key))
+				path = append(path, ']')
+			}
+		} else {
+			break
+		}
+	}
+
+	return string(path)
+}
+
+func isPathName(key []byte) bool {
+	if len(key) == 0 || (key[0] >= '0' && key[0] <= '9') {
+		return false
+	}
+	for _, c := range key {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '$') {
+			return false
+		}
+	}
+	return true
+}
+
+// how many bytes on each side of the error snippetAt keeps.
+const snippetContext = 40
+
+// snippetAt returns the line of data around off, cut to snippetContext
+// bytes on each side, and where off is in it.
+func snippetAt(data []byte, off int) (string, int) {
+	start := off
+	for start > 0 && data[start-1] != '\n' && off-start < snippetContext {
+		start--
+	}
+
+	end := off
+	for end < len(data) && data[end] != '\n' && end-off < snippetContext {
+		end++
+	}
+
+	line := []byte(string(data[start:end]))
+	for i, c := range line {
+		if c == '\t' || c == '\r' {
+			line[i] = ' '
+		}
+	}
+
+	return string(line), off - start
+}
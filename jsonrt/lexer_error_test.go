@@ -0,0 +1,82 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jsonrt
+
+import (
+	"errors"
+	"testing"
+)
+
+func scanToError(ffl *FFLexer) error {
+	for {
+		tok, err := ffl.Scan(false)
+		if err != nil || tok == FFTok_eof {
+			return err
+		}
+	}
+}
+
+func TestLexerErrorPath(t *testing.T) {
+	tests := []struct {
+		input string
+		path  string
+	}{
+		{`{"items": [{"price": 1}, {"price": 2}, {}, {"price": 1.x}]}`, `$.items[3].price`},
+		{`[1, 2, nul]`, `$[2]`},
+		{`{"a b": {"c": tru}}`, `$["a b"].c`},
+		{`{"a": 1, x}`, `$`},
+		{`@`, `$`},
+	}
+
+	for _, tt := range tests {
+		ffl := NewFFLexer([]byte(tt.input))
+		err := ffl.WrapErr(scanToError(ffl))
+
+		var le *LexerError
+		if !errors.As(err, &le) {
+			t.Fatalf("expected a *LexerError for %s, got %T", tt.input, err)
+		}
+		if le.Path() != tt.path {
+			t.Fatalf("expected path %s for %s, got %s", tt.path, tt.input, le.Path())
+		}
+	}
+}
+
+func TestLexerErrorPosition(t *testing.T) {
+	ffl := NewFFLexer([]byte("{\n  \"a\": [1,\n\t\"b\", nul]}"))
+	err := ffl.WrapErr(scanToError(ffl))
+
+	var le *LexerError
+	if !errors.As(err, &le) {
+		t.Fatalf("expected a *LexerError, got %T", err)
+	}
+
+	if le.Offset() != 22 || le.Line() != 3 || le.Column() != 9 {
+		t.Fatalf("unexpected position: offset=%d line=%d column=%d", le.Offset(), le.Line(), le.Column())
+	}
+
+	want := " \"b\", nul]}\n      ^"
+	if le.Snippet() != want {
+		t.Fatalf("unexpected snippet:\n%s\nwanted:\n%s", le.Snippet(), want)
+	}
+
+	var ffe *FFError
+	if !errors.As(err, &ffe) || ffe.Kind != FFErr_invalid_string {
+		t.Fatalf("expected the FFError to be reachable, got %v", err)
+	}
+}
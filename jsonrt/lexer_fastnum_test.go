@@ -0,0 +1,153 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+var fastNumberTests = []string{
+	"0", "-0", "1", "-1", "10", "120", "0.5", "-0.001", "1e3", "1E-3", "1.5e+300",
+	"123456789012345678", "1234567890123456789", "12345678901234567890",
+	"9223372036854775807", "-9223372036854775808", "9223372036854775808",
+	"18446744073709551615", "18446744073709551616",
+	"0.1", "0.30000000000000004", "3.141592653589793238462643383279",
+	"1e308", "1.7976931348623157e308", "1e309", "4.9e-324", "1e-400", "0e-100",
+	"100000000000000000000000", "2.2250738585072011e-308", "123.456e-7",
+}
+
+func TestFastNumber(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tests := append([]string(nil), fastNumberTests...)
+	for i := 0; i < 2000; i++ {
+		f := math.Float64frombits(r.Uint64())
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			continue
+		}
+		tests = append(tests, strconv.FormatFloat(f, 'g', -1, 64), strconv.FormatInt(r.Int63()>>uint(r.Intn(63)), 10))
+	}
+
+	for _, num := range tests {
+		ffl := NewFFLexer([]byte(num))
+		tok, err := ffl.Scan(false)
+		if err != nil || string(ffl.Output.Bytes()) != num {
+			t.Fatalf("unexpected token for %s: %v %v %s", num, tok, err, ffl.Output.Bytes())
+		}
+
+		ffl.Token = FFTok_double
+		f, err := ffl.ValueFloat()
+		wf, werr := ParseFloat([]byte(num), 64)
+		if math.Float64bits(f) != math.Float64bits(wf) || (err == nil) != (werr == nil) {
+			t.Fatalf("unexpected float64 for %s: %v %v, wanted %v %v", num, f, err, wf, werr)
+		}
+
+		f32, err := ffl.ValueFloat32()
+		wf, werr = ParseFloat([]byte(num), 32)
+		if math.Float32bits(f32) != math.Float32bits(float32(wf)) || (err == nil) != (werr == nil) {
+			t.Fatalf("unexpected float32 for %s: %v %v, wanted %v %v", num, f32, err, wf, werr)
+		}
+
+		if tok != FFTok_integer {
+			continue
+		}
+		ffl.Token = tok
+		for _, bits := range []int{8, 32, 64} {
+			i, err := ffl.ValueInt(bits)
+			wi, werr := ParseInt([]byte(num), 10, bits)
+			if i != wi || (err == nil) != (werr == nil) {
+				t.Fatalf("unexpected int%d for %s: %v %v, wanted %v %v", bits, num, i, err, wi, werr)
+			}
+
+			u, err := ffl.ValueUint(bits)
+			wu, werr := ParseUint([]byte(num), 10, bits)
+			if u != wu || (err == nil) != (werr == nil) {
+				t.Fatalf("unexpected uint%d for %s: %v %v, wanted %v %v", bits, num, u, err, wu, werr)
+			}
+		}
+	}
+}
+
+func TestFastNumberFallback(t *testing.T) {
+	tests := []struct {
+		input  string
+		syntax FFSyntax
+		toks   []FFTok
+	}{
+		{`01`, FFSyntax_default, []FFTok{FFTok_integer, FFTok_integer}},
+		{`[1,-2.5]`, FFSyntax_default, []FFTok{FFTok_left_brace, FFTok_integer, FFTok_comma, FFTok_double, FFTok_right_brace}},
+		{`5.`, FFSyntax_json5, []FFTok{FFTok_double}},
+		{`0x1F`, FFSyntax_json5, []FFTok{FFTok_integer}},
+		{`1e`, FFSyntax_default, []FFTok{FFTok_error}},
+		{`-`, FFSyntax_default, []FFTok{FFTok_error}},
+	}
+
+	for _, tt := range tests {
+		ffl := NewFFLexer([]byte(tt.input))
+		ffl.SetSyntax(tt.syntax)
+		for _, want := range tt.toks {
+			if tok, _ := ffl.Scan(false); tok != want {
+				t.Fatalf("unexpected token for %s: %v, wanted %v", tt.input, tok, want)
+			}
+		}
+	}
+
+	// numbers split by the window of a reader take the slow way.
+	input := bytes.Repeat([]byte("1234.5678,"), 1000)
+	ffl := NewFFLexerReader(bytes.NewReader(input))
+	for i := 0; i < 1000; i++ {
+		ffl.Scan(false)
+		f, err := ffl.ValueFloat()
+		ffl.Scan(false)
+		if err != nil || f != 1234.5678 {
+			t.Fatalf("unexpected float %d: %v %v", i, f, err)
+		}
+	}
+}
+
+func BenchmarkNumbers(b *testing.B) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; i < 1000; i++ {
+		buf.WriteString(strconv.Itoa(i * 7919))
+		buf.WriteString(", ")
+		buf.WriteString(strconv.FormatFloat(float64(i)*1.25e-3, 'g', -1, 64))
+		buf.WriteString(", ")
+	}
+	buf.WriteString("0]")
+	input := buf.Bytes()
+
+	ffl := NewFFLexer(input)
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		ffl.Reset(input)
+		err := ffl.ArrayEach(func(i int, tok FFTok) error {
+			if tok == FFTok_integer {
+				_, err := ffl.ValueInt(64)
+				return err
+			}
+			_, err := ffl.ValueFloat()
+			return err
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jsonrt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJson5Tokens(t *testing.T) {
+	input := `// config
+{
+  name: 'it\'s "x"',
+  $id_2: 0x1F,
+  neg: -0xff,
+  plus: +1,
+  lead: .5,
+  trail: 5.,
+  inf: Infinity,
+  ninf: -Infinity,
+  nan: NaN,
+  ok: true,
+  nothing: null,
+  list: [1, 2,],
+}`
+	ffl := NewFFLexer([]byte(input))
+	ffl.SetSyntax(FFSyntax_json5)
+
+	want := []struct {
+		tok FFTok
+		out string
+	}{
+		{FFTok_comment, ""},
+		{FFTok_left_bracket, ""},
+		{FFTok_string, "name"}, {FFTok_colon, ""}, {FFTok_string, `it's "x"`}, {FFTok_comma, ""},
+		{FFTok_string, "$id_2"}, {FFTok_colon, ""}, {FFTok_integer, "31"}, {FFTok_comma, ""},
+		{FFTok_string, "neg"}, {FFTok_colon, ""}, {FFTok_integer, "-255"}, {FFTok_comma, ""},
+		{FFTok_string, "plus"}, {FFTok_colon, ""}, {FFTok_integer, "1"}, {FFTok_comma, ""},
+		{FFTok_string, "lead"}, {FFTok_colon, ""}, {FFTok_double, "0.5"}, {FFTok_comma, ""},
+		{FFTok_string, "trail"}, {FFTok_colon, ""}, {FFTok_double, "5.0"}, {FFTok_comma, ""},
+		{FFTok_string, "inf"}, {FFTok_colon, ""}, {FFTok_double, "Infinity"}, {FFTok_comma, ""},
+		{FFTok_string, "ninf"}, {FFTok_colon, ""}, {FFTok_double, "-Infinity"}, {FFTok_comma, ""},
+		{FFTok_string, "nan"}, {FFTok_colon, ""}, {FFTok_double, "NaN"}, {FFTok_comma, ""},
+		{FFTok_string, "ok"}, {FFTok_colon, ""}, {FFTok_bool, "true"}, {FFTok_comma, ""},
+		{FFTok_string, "nothing"}, {FFTok_colon, ""}, {FFTok_null, "null"}, {FFTok_comma, ""},
+		{FFTok_string, "list"}, {FFTok_colon, ""}, {FFTok_left_brace, ""},
+		{FFTok_integer, "1"}, {FFTok_comma, ""}, {FFTok_integer, "2"}, {FFTok_comma, ""},
+		{FFTok_right_brace, ""}, {FFTok_comma, ""},
+		{FFTok_right_bracket, ""},
+	}
+
+	for i, w := range want {
+		tok, err := ffl.Scan(false)
+		if err != nil {
+			t.Fatalf("token %d: Scan failed: %v", i, err)
+		}
+		if tok != w.tok || ffl.Output.String() != w.out {
+			t.Fatalf("token %d: expected %v %q, got %v %q", i, w.tok, w.out, tok, ffl.Output.String())
+		}
+	}
+}
+
+func TestJson5ScanValues(t *testing.T) {
+	ffl := NewFFLexer([]byte(`{a: 0x10, b: +Infinity, c: '\x41\v'}`))
+	ffl.SetSyntax(FFSyntax_json5)
+
+	ffl.Scan(false)
+	ffl.Scan(false)
+	i, err := ffl.ScanIntValue(64)
+	if err != nil || i != 16 {
+		t.Fatalf("ScanIntValue: %v %v", i, err)
+	}
+	ffl.Scan(false)
+	ffl.Scan(false)
+	f, err := ffl.ScanFloatValue()
+	if err != nil || !math.IsInf(f, 1) {
+		t.Fatalf("ScanFloatValue: %v %v", f, err)
+	}
+	ffl.Scan(false)
+	ffl.Scan(false)
+	s, err := ffl.ScanStringValue()
+	if err != nil || s != "A\v" {
+		t.Fatalf("ScanStringValue: %q %v", s, err)
+	}
+}
+
+func TestJson5Buffer(t *testing.T) {
+	buf := NewBuffer(nil)
+	buf.SetJson5(true)
+	buf.AppendByte('{')
+	buf.AppendKey("name")
+	buf.AppendJsonString("x")
+	buf.AppendByte(',')
+	buf.AppendKey("a-b")
+	buf.AppendInt(1, 10)
+	buf.AppendByte(',')
+	buf.AppendKey("null")
+	buf.AppendBool(true)
+	buf.AppendByte(',')
+	buf.AppendEnd('}')
+
+	if buf.String() != `{name:"x","a-b":1,"null":true,}` {
+		t.Fatalf("unexpected JSON5: %s", buf.String())
+	}
+
+	buf = NewBuffer(nil)
+	buf.AppendByte('[')
+	buf.AppendEnd(']')
+	buf.AppendByte(',')
+	buf.AppendKey("k")
+	buf.AppendInt(2, 10)
+	buf.AppendByte(',')
+	buf.AppendEnd('}')
+	if buf.String() != `[],"k":2}` {
+		t.Fatalf("unexpected JSON: %s", buf.String())
+	}
+}
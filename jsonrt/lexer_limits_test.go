@@ -0,0 +1,151 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits FFLimits
+		ok     string
+		bad    string
+	}{
+		{"depth", FFLimits{MaxDepth: 2}, `[[1], {"a": 2}, []]`, `[[[1]]]`},
+		{"string", FFLimits{MaxStringBytes: 3}, `["abc", "é"]`, `["abcd"]`},
+		{"escaped", FFLimits{MaxStringBytes: 3}, `"a\nb"`, `"a\n\nb"`},
+		{"number", FFLimits{MaxNumberDigits: 4}, `[-1.5, 1234]`, `[12345]`},
+		{"tokens", FFLimits{MaxTokens: 5}, `[1, 2]`, `[1, 2, 3]`},
+		{"input", FFLimits{MaxInputBytes: 8}, `[1,2,3] `, `[1, 2, 3]`},
+	}
+
+	for _, tt := range tests {
+		ffl := NewFFLexer([]byte(tt.ok))
+		ffl.SetLimits(tt.limits)
+		if err := scanToError(ffl); err != io.EOF {
+			t.Fatalf("%s: unexpected error for %s: %v", tt.name, tt.ok, err)
+		}
+
+		ffl.Reset([]byte(tt.bad))
+		err := scanToError(ffl)
+		if !errors.Is(err, FFErr_limit_exceeded) {
+			t.Fatalf("%s: expected a limit error for %s, got %v", tt.name, tt.bad, err)
+		}
+	}
+}
+
+func TestLimitsSkipField(t *testing.T) {
+	ffl := NewFFLexer([]byte(strings.Repeat("[", 1000) + strings.Repeat("]", 1000)))
+	ffl.SetLimits(FFLimits{MaxDepth: 64})
+
+	tok, err := ffl.Scan(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = ffl.SkipField(tok)
+	if !errors.Is(err, FFErr_limit_exceeded) {
+		t.Fatalf("expected a limit error, got %v", err)
+	}
+}
+
+func TestLimitsReader(t *testing.T) {
+	input := `["` + strings.Repeat("x", 3*ffReaderBufSize) + `"]`
+
+	ffl := NewFFLexerReader(bytes.NewReader([]byte(input)))
+	ffl.SetLimits(FFLimits{MaxStringBytes: ffReaderBufSize})
+	if err := scanToError(ffl); !errors.Is(err, FFErr_limit_exceeded) {
+		t.Fatalf("expected a string limit error, got %v", err)
+	}
+
+	ffl.ResetReader(bytes.NewReader([]byte(input)))
+	ffl.SetLimits(FFLimits{MaxInputBytes: ffReaderBufSize})
+	if err := scanToError(ffl); !errors.Is(err, FFErr_limit_exceeded) {
+		t.Fatalf("expected an input limit error, got %v", err)
+	}
+}
+
+func TestLimitsNoCopy(t *testing.T) {
+	const size = 1 << 20
+	tests := []struct {
+		name   string
+		limits FFLimits
+		input  string
+	}{
+		{"string", FFLimits{MaxStringBytes: 16}, `"` + strings.Repeat("x", size) + `"`},
+		{"escaped", FFLimits{MaxStringBytes: 16}, `"` + strings.Repeat(`\n`, size) + `"`},
+		{"single", FFLimits{MaxStringBytes: 16}, `'` + strings.Repeat("x", size) + `'`},
+		{"number", FFLimits{MaxNumberDigits: 16}, strings.Repeat("1", size) + ` `},
+		{"fraction", FFLimits{MaxNumberDigits: 16}, `1.` + strings.Repeat("1", size) + ` `},
+	}
+
+	for _, tt := range tests {
+		for _, rd := range []bool{false, true} {
+			var ffl *FFLexer
+			if rd {
+				ffl = NewFFLexerReader(strings.NewReader(tt.input))
+			} else {
+				ffl = NewFFLexer([]byte(tt.input))
+			}
+			ffl.SetSyntax(FFSyntax_json5)
+			ffl.SetLimits(tt.limits)
+
+			_, err := ffl.Scan(false)
+			if !errors.Is(err, FFErr_limit_exceeded) {
+				t.Fatalf("%s (reader %v): expected a limit error, got %v", tt.name, rd, err)
+			}
+			if n := cap(ffl.Output.Bytes()); n > 1024 {
+				t.Fatalf("%s (reader %v): output grew to %d bytes", tt.name, rd, n)
+			}
+		}
+	}
+}
+
+// BenchmarkScanLimits shows what Scan pays for limits, which must be
+// nothing when none is set.
+func BenchmarkScanLimits(b *testing.B) {
+	input := []byte(`{"id": 12345, "name": "some name", "tags": ["a", "b", "c"], "price": 12.5, "ok": true, "nested": {"x": null, "y": [1, 2, 3]}, "note": "a \"quoted\" text"}`)
+
+	for _, bm := range []struct {
+		name   string
+		limits FFLimits
+	}{
+		{"none", FFLimits{}},
+		{"all", FFLimits{MaxDepth: 64, MaxStringBytes: 1 << 20, MaxNumberDigits: 64, MaxTokens: 1 << 20, MaxInputBytes: 1 << 30}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			ffl := NewFFLexer(input)
+			ffl.SetLimits(bm.limits)
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				ffl.Reset(input)
+				for {
+					tok, _ := ffl.Scan(false)
+					if tok == FFTok_eof || tok == FFTok_error {
+						break
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestPeek(t *testing.T) {
+	ffl := NewFFLexer([]byte(`{"a": 1}`))
+
+	tok, err := ffl.Scan(false)
+	if err != nil || tok != FFTok_left_bracket {
+		t.Fatalf("unexpected token: %v %v", tok, err)
+	}
+
+	tok, err = ffl.Peek()
+	if err != nil || tok != FFTok_string {
+		t.Fatalf("unexpected peeked token: %v %v", tok, err)
+	}
+	if ffl.Token != FFTok_left_bracket {
+		t.Fatalf("expected Token to be kept, got %v", ffl.Token)
+	}
+
+	tok, err = ffl.Scan(false)
+	if err != nil || tok != FFTok_string || string(ffl.Output.Bytes()) != "a" {
+		t.Fatalf("unexpected token after Peek: %v %v %s", tok, err, ffl.Output.Bytes())
+	}
+}
+
+func TestPeekAllocs(t *testing.T) {
+	ffl := NewFFLexer([]byte(`{"a": "b"}`))
+	for i := 0; i < 2; i++ {
+		if _, err := ffl.Scan(false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if tok, err := ffl.Peek(); err != nil || tok != FFTok_colon {
+			t.Fatalf("unexpected peeked token: %v %v", tok, err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected Peek not to allocate, got %v allocs", allocs)
+	}
+	if string(ffl.Output.Bytes()) != "a" {
+		t.Fatalf("expected Output to be kept, got %s", ffl.Output.Bytes())
+	}
+}
+
+func TestMarkRestore(t *testing.T) {
+	ffl := NewFFLexer([]byte(`: "12", "x"`))
+
+	m := ffl.Mark()
+	if _, err := ffl.ScanIntValue(64); err == nil {
+		t.Fatalf("expected an int not to decode")
+	}
+
+	ffl.Restore(m)
+	s, err := ffl.ScanStringValue()
+	if err != nil || s != "12" {
+		t.Fatalf("unexpected value after Restore: %q %v", s, err)
+	}
+	ffl.Release(m)
+
+	tok, err := ffl.Scan(false)
+	if err != nil || tok != FFTok_comma {
+		t.Fatalf("unexpected token: %v %v", tok, err)
+	}
+}
+
+func TestMarkRestoreReader(t *testing.T) {
+	input := `[` + strings.Repeat(`"abcdefgh", `, ffReaderBufSize) + `1]`
+	ffl := NewFFLexerReader(iotest.OneByteReader(bytes.NewReader([]byte(input))))
+
+	if tok, err := ffl.Scan(false); err != nil || tok != FFTok_left_brace {
+		t.Fatalf("unexpected token: %v %v", tok, err)
+	}
+
+	m := ffl.Mark()
+	n := 0
+	for {
+		tok, err := ffl.Scan(false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tok == FFTok_integer {
+			break
+		}
+		n++
+	}
+
+	ffl.Restore(m)
+	ffl.Release(m)
+
+	tok, err := ffl.Scan(false)
+	if err != nil || tok != FFTok_string || string(ffl.Output.Bytes()) != "abcdefgh" {
+		t.Fatalf("unexpected token after Restore: %v %v %s", tok, err, ffl.Output.Bytes())
+	}
+	if n != 2*ffReaderBufSize {
+		t.Fatalf("unexpected token count: %d", n)
+	}
+}
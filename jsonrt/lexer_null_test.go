@@ -0,0 +1,75 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScanValueOrNull(t *testing.T) {
+	ffl := NewFFLexer([]byte(`: null, : 5, : "x", : true, : 1.5, : null, : "7"`))
+
+	checkNull := func(isNull bool, err error, want bool) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if isNull != want {
+			t.Fatalf("expected isNull %v", want)
+		}
+		ffl.Scan(false) // the comma
+	}
+
+	i, isNull, err := ffl.ScanIntValueOrNull(64)
+	checkNull(isNull, err, true)
+
+	i, isNull, err = ffl.ScanIntValueOrNull(64)
+	checkNull(isNull, err, false)
+	if i != 5 {
+		t.Fatalf("unexpected int: %d", i)
+	}
+
+	s, isNull, err := ffl.ScanStringValueOrNull()
+	checkNull(isNull, err, false)
+	if s != "x" {
+		t.Fatalf("unexpected string: %s", s)
+	}
+
+	b, isNull, err := ffl.ScanBoolValueOrNull()
+	checkNull(isNull, err, false)
+	if !b {
+		t.Fatalf("unexpected bool: %v", b)
+	}
+
+	f, isNull, err := ffl.ScanFloatValueOrNull()
+	checkNull(isNull, err, false)
+	if f != 1.5 {
+		t.Fatalf("unexpected float: %v", f)
+	}
+
+	u, isNull, err := ffl.ScanUintValueOrNull(64)
+	checkNull(isNull, err, true)
+	if u != 0 {
+		t.Fatalf("unexpected uint: %v", u)
+	}
+
+	_, isNull, err = ffl.ScanUintValueOrNull(64)
+	if isNull || !errors.Is(err, FFErr_unexpected_token_type) {
+		t.Fatalf("expected a type error, got %v %v", isNull, err)
+	}
+}
@@ -0,0 +1,94 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"errors"
+	"strconv"
+)
+
+// The Scan*Value helpers for each Go number type. A value out of range of
+// the type is a *NumError wrapping ErrRange, wrapped by WrapErr so it
+// tells the path of the field.
+
+func (ffl *FFLexer) ScanInt8Value() (int8, error) {
+	v, err := ffl.ScanIntValue(8)
+	return int8(v), ffl.rangeErr(err)
+}
+
+func (ffl *FFLexer) ScanUint8Value() (uint8, error) {
+	v, err := ffl.ScanUintValue(8)
+	return uint8(v), ffl.rangeErr(err)
+}
+
+func (ffl *FFLexer) ScanInt16Value() (int16, error) {
+	v, err := ffl.ScanIntValue(16)
+	return int16(v), ffl.rangeErr(err)
+}
+
+func (ffl *FFLexer) ScanUint16Value() (uint16, error) {
+	v, err := ffl.ScanUintValue(16)
+	return uint16(v), ffl.rangeErr(err)
+}
+
+func (ffl *FFLexer) ScanInt32Value() (int32, error) {
+	v, err := ffl.ScanIntValue(32)
+	return int32(v), ffl.rangeErr(err)
+}
+
+func (ffl *FFLexer) ScanUint32Value() (uint32, error) {
+	v, err := ffl.ScanUintValue(32)
+	return uint32(v), ffl.rangeErr(err)
+}
+
+func (ffl *FFLexer) ScanInt64Value() (int64, error) {
+	v, err := ffl.ScanIntValue(64)
+	return v, ffl.rangeErr(err)
+}
+
+func (ffl *FFLexer) ScanUint64Value() (uint64, error) {
+	v, err := ffl.ScanUintValue(64)
+	return v, ffl.rangeErr(err)
+}
+
+func (ffl *FFLexer) ScanNativeIntValue() (int, error) {
+	v, err := ffl.ScanIntValue(strconv.IntSize)
+	return int(v), ffl.rangeErr(err)
+}
+
+func (ffl *FFLexer) ScanNativeUintValue() (uint, error) {
+	v, err := ffl.ScanUintValue(strconv.IntSize)
+	return uint(v), ffl.rangeErr(err)
+}
+
+// ScanFloat32Value is ScanFloatValue for a float32, the number is rounded
+// to float32 directly, not through a float64.
+func (ffl *FFLexer) ScanFloat32Value() (float32, error) {
+	if err := ffl.scanFieldValue(); err != nil {
+		return 0, err
+	}
+	v, err := ffl.ValueFloat32()
+	return v, ffl.rangeErr(err)
+}
+
+// rangeErr wraps err with WrapErr if it is a range error.
+func (ffl *FFLexer) rangeErr(err error) error {
+	if err != nil && errors.Is(err, ErrRange) {
+		return ffl.WrapErr(err)
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestScanTypedInts(t *testing.T) {
+	ffl := NewFFLexer([]byte(`: -128, : 255, : -32768, : 65535, : 2147483647, : 4294967295, : -1, : 1`))
+	next := func() { ffl.Scan(false) }
+
+	i8, err := ffl.ScanInt8Value()
+	if err != nil || i8 != math.MinInt8 {
+		t.Fatalf("unexpected int8: %d %v", i8, err)
+	}
+	next()
+	u8, err := ffl.ScanUint8Value()
+	if err != nil || u8 != math.MaxUint8 {
+		t.Fatalf("unexpected uint8: %d %v", u8, err)
+	}
+	next()
+	i16, err := ffl.ScanInt16Value()
+	if err != nil || i16 != math.MinInt16 {
+		t.Fatalf("unexpected int16: %d %v", i16, err)
+	}
+	next()
+	u16, err := ffl.ScanUint16Value()
+	if err != nil || u16 != math.MaxUint16 {
+		t.Fatalf("unexpected uint16: %d %v", u16, err)
+	}
+	next()
+	i32, err := ffl.ScanInt32Value()
+	if err != nil || i32 != math.MaxInt32 {
+		t.Fatalf("unexpected int32: %d %v", i32, err)
+	}
+	next()
+	u32, err := ffl.ScanUint32Value()
+	if err != nil || u32 != math.MaxUint32 {
+		t.Fatalf("unexpected uint32: %d %v", u32, err)
+	}
+	next()
+	i, err := ffl.ScanNativeIntValue()
+	if err != nil || i != -1 {
+		t.Fatalf("unexpected int: %d %v", i, err)
+	}
+	next()
+	u, err := ffl.ScanNativeUintValue()
+	if err != nil || u != 1 {
+		t.Fatalf("unexpected uint: %d %v", u, err)
+	}
+}
+
+func TestScanTypedRange(t *testing.T) {
+	ffl := NewFFLexer([]byte(`{"items": [{"n": 1}, {"n": 128}]}`))
+	for i := 0; i < 12; i++ {
+		ffl.Scan(false)
+	}
+
+	_, err := ffl.ScanInt8Value()
+	if !errors.Is(err, ErrRange) {
+		t.Fatalf("expected a range error, got %v", err)
+	}
+
+	var le *LexerError
+	if !errors.As(err, &le) || le.Path() != "$.items[1].n" {
+		t.Fatalf("expected the path of the field, got %v", err)
+	}
+	if ffl.WrapErr(err) != err {
+		t.Fatalf("expected WrapErr to keep a wrapped error")
+	}
+}
+
+func TestScanFloat32Value(t *testing.T) {
+	// rounding through float64 would give the next float32 up.
+	ffl := NewFFLexer([]byte(`: 1.000000059604644775390625000001, : 3.4e39, : 16777217`))
+
+	f, err := ffl.ScanFloat32Value()
+	if err != nil || f != float32(1.0000001) {
+		t.Fatalf("unexpected float32: %v %v", f, err)
+	}
+	ffl.Scan(false)
+
+	_, err = ffl.ScanFloat32Value()
+	if !errors.Is(err, ErrRange) {
+		t.Fatalf("expected a range error, got %v", err)
+	}
+	ffl.Scan(false)
+
+	ffl.SetCoerce(FFCoerce_int_to_float)
+	_, err = ffl.ScanFloat32Value()
+	if !errors.Is(err, ErrPrecision) {
+		t.Fatalf("expected a precision error, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jsonrt
+
+import (
+	"strings"
+	"testing"
+)
+
+// scanPushed feeds input to a push lexer n bytes at a time, whenever
+// Scan asks for more.
+func scanPushed(t *testing.T, input string, n int) ([]FFTok, []string) {
+	return scanPushedLexer(t, NewFFLexerPush(), input, n)
+}
+
+// scanPushedLexer is scanPushed with a push lexer set up by the caller.
+func scanPushedLexer(t *testing.T, ffl *FFLexer, input string, n int) ([]FFTok, []string) {
+	var toks []FFTok
+	var outs []string
+	for {
+		tok, err := ffl.Scan(false)
+		if tok == FFTok_need_more {
+			if err != ErrNeedMore {
+				t.Fatalf("expected ErrNeedMore, got %v", err)
+			}
+			if len(input) == 0 {
+				ffl.FeedEOF()
+				continue
+			}
+			if n > len(input) {
+				n = len(input)
+			}
+			ffl.Feed([]byte(input[:n]))
+			input = input[n:]
+			continue
+		}
+		if tok == FFTok_eof {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		toks = append(toks, tok)
+		outs = append(outs, ffl.Output.String())
+	}
+	return toks, outs
+}
+
+func TestPushLexerSplitTokens(t *testing.T) {
+	input := `{"hello": "wérld 𐐷", "n": -12.5e+3, "t": true, "f": false, "z": null /* c */ , "a": [1, 22, 333]}`
+	wantToks, wantOuts := scanAllOutputs(t, NewFFLexer([]byte(input)))
+
+	for _, n := range []int{1, 2, 3, 7, len(input)} {
+		toks, outs := scanPushed(t, input, n)
+		if len(toks) != len(wantToks) {
+			t.Fatalf("chunk size %d: expected %d tokens, got %d", n, len(wantToks), len(toks))
+		}
+		for i := range toks {
+			if toks[i] != wantToks[i] || outs[i] != wantOuts[i] {
+				t.Fatalf("chunk size %d, token %d: expected %v %q, got %v %q",
+					n, i, wantToks[i], wantOuts[i], toks[i], outs[i])
+			}
+		}
+	}
+}
+
+func TestPushLexerNumberAtEOF(t *testing.T) {
+	ffl := NewFFLexerPush()
+	ffl.Feed([]byte(`123`))
+
+	tok, err := ffl.Scan(false)
+	if tok != FFTok_need_more || err != ErrNeedMore {
+		t.Fatalf("expected need_more for an unterminated number, got %v %v", tok, err)
+	}
+
+	ffl.Feed([]byte(`4`))
+	ffl.FeedEOF()
+
+	tok, err = ffl.Scan(false)
+	if err != nil || tok != FFTok_integer || ffl.Output.String() != "1234" {
+		t.Fatalf("expected integer 1234, got %v %q %v", tok, ffl.Output.String(), err)
+	}
+
+	tok, _ = ffl.Scan(false)
+	if tok != FFTok_eof {
+		t.Fatalf("expected eof, got %v", tok)
+	}
+}
+
+func TestPushLexerSplitEscapesAndComments(t *testing.T) {
+	input := `{"a\"b": "x\u00e9\ud801\udc37\n\/y", // line
+	'c': 'd\x41\'e', /* block ** */ "k": [1] /**/}`
+
+	for _, keep := range []bool{false, true} {
+		want := NewFFLexer([]byte(input))
+		want.SetSyntax(FFSyntax_json5)
+		want.SetKeepComments(keep)
+		wantToks, wantOuts := scanAllOutputs(t, want)
+
+		for n := 1; n < 8; n++ {
+			ffl := NewFFLexerPush()
+			ffl.SetSyntax(FFSyntax_json5)
+			ffl.SetKeepComments(keep)
+			toks, outs := scanPushedLexer(t, ffl, input, n)
+			if strings.Join(outs, "|") != strings.Join(wantOuts, "|") || len(toks) != len(wantToks) {
+				t.Fatalf("chunk size %d, keep %v: expected %q, got %q", n, keep, wantOuts, outs)
+			}
+		}
+	}
+}
+
+func TestPushLexerLongTokenLinear(t *testing.T) {
+	const size = 200 << 10
+	tokens := []string{
+		`"` + strings.Repeat(`abc\n`, size/5) + `"`,
+		`/*` + strings.Repeat(`*x`, size/2) + `*/`,
+	}
+
+	for _, token := range tokens {
+		ffl := NewFFLexerPush()
+		ffl.SetKeepComments(true)
+		input := token + ` `
+		fed := 0
+		for {
+			tok, err := ffl.Scan(false)
+			if tok != FFTok_need_more {
+				if err != nil || ffl.Output.Len() == 0 {
+					t.Fatalf("unexpected token: %v %v", tok, err)
+				}
+				break
+			}
+
+			// the token goes on where it stopped: all that was fed is
+			// consumed, and the window does not hold the whole token.
+			if pos := ffl.reader.Pos(); fed > 64 && pos < fed-8 {
+				t.Fatalf("lexer went back to %d of %d", pos, fed)
+			}
+			if n := cap(ffl.reader.s); n > 4*ffReaderBufSize {
+				t.Fatalf("window grew to %d bytes", n)
+			}
+
+			n := 64
+			if n > len(input) {
+				n = len(input)
+			}
+			ffl.Feed([]byte(input[:n]))
+			input = input[n:]
+			fed += n
+		}
+	}
+}
+
+func TestPushLexerCaptureSplitString(t *testing.T) {
+	input := `"a\nbéc" `
+	want := NewFFLexer([]byte(input))
+	want.Scan(true)
+
+	ffl := NewFFLexerPush()
+	for i := 0; ; {
+		tok, err := ffl.Scan(true)
+		if tok != FFTok_need_more {
+			if err != nil || ffl.Output.String() != want.Output.String() {
+				t.Fatalf("expected %q, got %q %v", want.Output.String(), ffl.Output.String(), err)
+			}
+			break
+		}
+		ffl.Feed([]byte(input[i : i+1]))
+		i++
+	}
+}
@@ -0,0 +1,86 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCaptureRaw(t *testing.T) {
+	input := []byte(`{"a": {"s": "\/é\"" , "n": [1.50, {}]}, "b": "x\/y", "c": 1E2}`)
+	ffl := NewFFLexer(input)
+
+	want := map[string]string{
+		"a": `{"s": "\/é\"" , "n": [1.50, {}]}`,
+		"b": `"x\/y"`,
+		"c": `1E2`,
+	}
+
+	err := ffl.ObjectEach(func(key []byte, tok FFTok) error {
+		raw, err := ffl.CaptureRaw(tok)
+		if err != nil {
+			return err
+		}
+		if string(raw) != want[string(key)] {
+			t.Fatalf("unexpected raw value of %s: %s", key, raw)
+		}
+		if &raw[0] != &input[bytes.Index(input, raw)] {
+			t.Fatalf("expected a slice of the input for %s", key)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCaptureRawAllocs(t *testing.T) {
+	input := []byte(`{"a": [1, "two", {"three": 3.0}], "b": null}`)
+	ffl := NewFFLexer(input)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		ffl.Reset(input)
+		tok, _ := ffl.Scan(false)
+		if raw, err := ffl.CaptureRaw(tok); err != nil || len(raw) != len(input) {
+			t.Fatalf("unexpected capture: %s %v", raw, err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestCaptureRawReader(t *testing.T) {
+	value := `{"k": [` + strings.Repeat(`"Abc", `, 2*ffReaderBufSize) + `0]}`
+	input := `[1, ` + value + `, 2]`
+	ffl := NewFFLexerReader(iotest.OneByteReader(strings.NewReader(input)))
+
+	for i := 0; i < 4; i++ {
+		ffl.Scan(false)
+	}
+	raw, err := ffl.CaptureRaw(ffl.Token)
+	if err != nil || string(raw) != value {
+		t.Fatalf("unexpected capture: %d bytes, %v", len(raw), err)
+	}
+
+	if _, err = ffl.CaptureRaw(FFTok_comma); err == nil {
+		t.Fatalf("expected an error for a comma")
+	}
+}
@@ -0,0 +1,156 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jsonrt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const tReaderDoc = `{"a": 1, "b": -2.5e3, "s": "héllo \"w\" 𐐷",
+ "arr": [true, false, null, {"x": "y"}], "long": "` + `0123456789abcdefghijklmnopqrstuvwxyz` + `"}`
+
+func scanAllOutputs(t *testing.T, ffl *FFLexer) ([]FFTok, []string) {
+	var toks []FFTok
+	var outs []string
+	for {
+		tok, err := ffl.Scan(false)
+		if tok == FFTok_eof {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		toks = append(toks, tok)
+		outs = append(outs, ffl.Output.String())
+	}
+	return toks, outs
+}
+
+func TestReaderLexerMatchesBytes(t *testing.T) {
+	wantToks, wantOuts := scanAllOutputs(t, NewFFLexer([]byte(tReaderDoc)))
+
+	ffl := NewFFLexerReader(iotest.OneByteReader(strings.NewReader(tReaderDoc)))
+	toks, outs := scanAllOutputs(t, ffl)
+
+	if len(toks) != len(wantToks) {
+		t.Fatalf("expected %d tokens, got %d", len(wantToks), len(toks))
+	}
+	for i := range toks {
+		if toks[i] != wantToks[i] || outs[i] != wantOuts[i] {
+			t.Fatalf("token %d: expected %v %q, got %v %q", i, wantToks[i], wantOuts[i], toks[i], outs[i])
+		}
+	}
+}
+
+func TestReaderLexerScanValues(t *testing.T) {
+	ffl := NewFFLexerReader(iotest.HalfReader(strings.NewReader(`{"a" : 42, "b": "x\ty", "c": {"d": [1, 2]}, "e": 1.5}`)))
+
+	if tok, _ := ffl.Scan(false); tok != FFTok_left_bracket {
+		t.Fatalf("expected {, got %v", tok)
+	}
+	ffl.Scan(false)
+	i, err := ffl.ScanIntValue(64)
+	if err != nil || i != 42 {
+		t.Fatalf("ScanIntValue: %v %v", i, err)
+	}
+	ffl.Scan(false)
+	ffl.Scan(false)
+	s, err := ffl.ScanStringValue()
+	if err != nil || s != "x\ty" {
+		t.Fatalf("ScanStringValue: %q %v", s, err)
+	}
+	ffl.Scan(false)
+	ffl.Scan(false)
+	tok, err := ffl.ScanToValue()
+	if err != nil {
+		t.Fatalf("ScanToValue: %v", err)
+	}
+	if err := ffl.SkipField(tok); err != nil {
+		t.Fatalf("SkipField: %v", err)
+	}
+	ffl.Scan(false)
+	ffl.Scan(false)
+	f, err := ffl.ScanFloatValue()
+	if err != nil || f != 1.5 {
+		t.Fatalf("ScanFloatValue: %v %v", f, err)
+	}
+}
+
+func TestReaderLexerCapture(t *testing.T) {
+	ffl := NewFFLexerReader(iotest.OneByteReader(strings.NewReader(`{"hello": {"blah": [null, 1]}}`)))
+	ffl.Scan(false)
+	ffl.Scan(false)
+	tok, err := ffl.ScanToValue()
+	if err != nil {
+		t.Fatalf("ScanToValue: %v", err)
+	}
+	buf, err := ffl.CaptureField(tok)
+	if err != nil {
+		t.Fatalf("CaptureField failed: %v", err)
+	}
+	if !bytes.Equal(buf, []byte(`{"blah": [null, 1]}`)) {
+		t.Fatalf("didnt capture subfield: buf: %v", string(buf))
+	}
+}
+
+func TestReaderLexerErrorPosition(t *testing.T) {
+	input := strings.Repeat(" ", 5000) + "\n\n  {\"a\": nul}"
+	ffl := NewFFLexerReader(strings.NewReader(input))
+
+	var err error
+	for err == nil {
+		_, err = ffl.Scan(false)
+	}
+
+	le, ok := ffl.WrapErr(err).(*LexerError)
+	if !ok {
+		t.Fatalf("expected *LexerError, got %T", ffl.WrapErr(err))
+	}
+	if le.offset != len(input)-1 || le.line != 3 || le.char != 11 {
+		t.Fatalf("unexpected position: offset=%d line=%d char=%d", le.offset, le.line, le.char)
+	}
+}
+
+func TestReaderLexerReadError(t *testing.T) {
+	boom := errors.New("boom")
+	ffl := NewFFLexerReader(&errAfterReader{r: strings.NewReader(`[tr`), err: boom})
+
+	ffl.Scan(false)
+	_, err := ffl.Scan(false)
+	ffe, ok := err.(*FFError)
+	if !ok || ffe.Kind != FFErr_io || ffe.Err != boom {
+		t.Fatalf("expected io error wrapping boom, got %v", err)
+	}
+}
+
+type errAfterReader struct {
+	r   *strings.Reader
+	err error
+}
+
+func (e *errAfterReader) Read(p []byte) (int, error) {
+	n, _ := e.r.Read(p)
+	if n == 0 {
+		return 0, e.err
+	}
+	return n, nil
+}
@@ -0,0 +1,251 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// classes of the bytes skipContainer stops at outside of strings.
+const (
+	skipOpen = 1 + iota
+	skipClose
+	skipQuote
+	skipSingleQuote
+	skipSlash
+)
+
+var skipTable = [256]byte{
+	'{':  skipOpen,
+	'[':  skipOpen,
+	'}':  skipClose,
+	']':  skipClose,
+	'"':  skipQuote,
+	'\'': skipSingleQuote,
+	'/':  skipSlash,
+}
+
+// fastSkip reports whether SkipField may use skipContainer: it checks
+// nothing but the brackets, so strict syntax and the token, string and
+// number limits need every token scanned. In push mode a skip can not
+// wait for more input halfway.
+func (ffl *FFLexer) fastSkip() bool {
+	return ffl.syntax != FFSyntax_strict && !ffl.reader.push &&
+		ffl.limits.MaxTokens == 0 &&
+		ffl.limits.MaxStringBytes == 0 &&
+		ffl.limits.MaxNumberDigits == 0
+}
+
+// skipContainer skips the rest of an object or array whose first bracket,
+// start, was scanned last, like SkipField, without scanning the tokens in
+// it. Strings, escapes and comments are followed only far enough to match
+// the brackets right.
+func (ffl *FFLexer) skipContainer(start FFTok) error {
+	r := ffl.reader
+	json5 := ffl.syntax == FFSyntax_json5
+	depth := 1
+
+	// one bit per open bracket, set for a '{', to match the closing ones.
+	var small [1]uint64
+	kinds := small[:]
+	if start == FFTok_left_bracket {
+		kinds[0] = 1
+	}
+
+	var err error
+	j := r.i
+	for {
+		if j >= r.l {
+			if j, err = r.skipMore(j); err != nil {
+				return err
+			}
+		}
+
+		c := r.s[j]
+		j++
+
+		switch skipTable[c] {
+		case skipOpen:
+			if depth>>6 == len(kinds) {
+				kinds = append(kinds, 0)
+			}
+			if bit := uint64(1) << uint(depth&63); c == '{' {
+				kinds[depth>>6] |= bit
+			} else {
+				kinds[depth>>6] &^= bit
+			}
+			depth++
+			if max := ffl.limits.MaxDepth; max > 0 && ffl.depth+depth-1 > max {
+				r.i = j
+				return limitError("depth", max)
+			}
+		case skipClose:
+			depth--
+			if object := kinds[depth>>6]>>uint(depth&63)&1 != 0; object != (c == '}') {
+				r.i = j
+				return &UnexpectedTokenError{Expected: closeTok(object), Got: closeTok(!object)}
+			}
+			if depth == 0 {
+				r.i = j
+				ffl.closeSkip(c)
+				return nil
+			}
+		case skipQuote:
+			j, err = r.skipString(j, '"')
+		case skipSingleQuote:
+			if json5 {
+				j, err = r.skipString(j, '\'')
+			}
+		case skipSlash:
+			j, err = r.skipComment(j)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// closeSkip leaves the lexer as if it had scanned c, the closing bracket
+// skipContainer stopped at.
+func (ffl *FFLexer) closeSkip(c byte) {
+	ffl.outputbuf.Reset()
+	ffl.hasView = false
+	ffl.hasNum = false
+	ffl.tokStart = ffl.reader.Pos() - 1
+	ffl.Token = closeTok(c == '}')
+	if ffl.depth > 0 {
+		ffl.depth--
+	}
+}
+
+// closeTok returns the closing token of an object, or else of an array.
+func closeTok(object bool) FFTok {
+	if object {
+		return FFTok_right_bracket
+	}
+	return FFTok_right_brace
+}
+
+// skipMore reads more input for a skip at j, running into the end of the
+// input there is an ErrUnexpectedEOF.
+func (r *ffReader) skipMore(j int) (int, error) {
+	r.i = j
+	j, err := r.ensure(j, 1)
+	r.i = j
+	if err != nil {
+		return j, ioError(err)
+	}
+	if j >= r.l {
+		return j, newEOFError()
+	}
+	return j, nil
+}
+
+const (
+	swarLo = 0x0101010101010101
+	swarHi = 0x8080808080808080
+)
+
+// swarFind returns a word with the high bit set in each byte of w which is
+// c. Only the lowest one is exact, there may be false ones above it.
+func swarFind(w uint64, c byte) uint64 {
+	x := w ^ (swarLo * uint64(c))
+	return (x - swarLo) &^ x & swarHi
+}
+
+// skipString skips a string from j, just after the opening quote q, to
+// just after its closing quote, 8 bytes at a time where it can.
+func (r *ffReader) skipString(j int, q byte) (int, error) {
+	var err error
+	for {
+		for j+8 <= r.l {
+			w := binary.LittleEndian.Uint64(r.s[j:])
+			if m := swarFind(w, q) | swarFind(w, '\\'); m != 0 {
+				j += bits.TrailingZeros64(m) >> 3
+				break
+			}
+			j += 8
+		}
+
+		if j >= r.l {
+			if j, err = r.skipMore(j); err != nil {
+				return j, err
+			}
+		}
+
+		c := r.s[j]
+		j++
+		if c == q {
+			return j, nil
+		}
+
+		if c == '\\' {
+			// the escaped byte, only a quote or a backslash matters.
+			if j >= r.l {
+				if j, err = r.skipMore(j); err != nil {
+					return j, err
+				}
+			}
+			j++
+		}
+	}
+}
+
+// skipComment skips a comment from j, just after its '/'. A '/' which does
+// not start a comment is skipped like any other byte.
+func (r *ffReader) skipComment(j int) (int, error) {
+	var err error
+	if j >= r.l {
+		if j, err = r.skipMore(j); err != nil {
+			return j, err
+		}
+	}
+
+	switch r.s[j] {
+	case '/':
+		for {
+			j++
+			if j >= r.l {
+				if j, err = r.skipMore(j); err != nil {
+					return j, err
+				}
+			}
+			if r.s[j] == '\n' {
+				return j + 1, nil
+			}
+		}
+	case '*':
+		j++
+		star := false
+		for {
+			if j >= r.l {
+				if j, err = r.skipMore(j); err != nil {
+					return j, err
+				}
+			}
+			c := r.s[j]
+			j++
+			if star && c == '/' {
+				return j, nil
+			}
+			star = c == '*'
+		}
+	}
+	return j, nil
+}
@@ -0,0 +1,155 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// skipAll skips the value in input, which is followed by ", 7]", and
+// checks that the lexer is left on its closing bracket.
+func skipAll(ffl *FFLexer) error {
+	ffl.Scan(false)
+	tok, err := ffl.Scan(false)
+	if err != nil {
+		return err
+	}
+	if err = ffl.SkipField(tok); err != nil {
+		return err
+	}
+	if tok == FFTok_left_bracket && ffl.Token != FFTok_right_bracket ||
+		tok == FFTok_left_brace && ffl.Token != FFTok_right_brace {
+		return errors.New("not left on the closing bracket: " + ffl.Token.String())
+	}
+
+	if tok, _ = ffl.Scan(false); tok != FFTok_comma {
+		return errors.New("expected a comma, got " + tok.String())
+	}
+	if tok, _ = ffl.Scan(false); tok != FFTok_integer || ffl.Output.String() != "7" {
+		return errors.New("expected 7, got " + tok.String())
+	}
+	return nil
+}
+
+func TestSkipField(t *testing.T) {
+	tests := []struct {
+		value  string
+		syntax FFSyntax
+	}{
+		{`{}`, FFSyntax_default},
+		{`{"a": [1, 2.5e3, {"b": null}], "c": true}`, FFSyntax_default},
+		{`["]", "}", "\"]", "\\", "]"]`, FFSyntax_default},
+		{`{"a": "` + strings.Repeat(`x\"[`, 50) + `"}`, FFSyntax_default},
+		{`[1, /* ] */ 2 // ]
+		]`, FFSyntax_default},
+		{`{a: ['\'}', "'"], b: +1,}`, FFSyntax_json5},
+	}
+
+	for _, tt := range tests {
+		for _, slow := range []bool{false, true} {
+			ffl := NewFFLexer([]byte(`[` + tt.value + `, 7]`))
+			ffl.SetSyntax(tt.syntax)
+			if slow {
+				ffl.SetLimits(FFLimits{MaxTokens: 1000})
+			}
+			if err := skipAll(ffl); err != nil {
+				t.Errorf("%s (slow %v): %v", tt.value, slow, err)
+			}
+		}
+	}
+}
+
+func TestSkipFieldReader(t *testing.T) {
+	value := `{"k": [` + strings.Repeat(`"a\"b]", {"c": [/**/]}, `, 2*ffReaderBufSize) + `0]}`
+	ffl := NewFFLexerReader(iotest.OneByteReader(strings.NewReader(`[` + value + `, 7]`)))
+	if err := skipAll(ffl); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSkipFieldErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		limits FFLimits
+		syntax FFSyntax
+		err    error
+	}{
+		{`{"a": [1, "x`, FFLimits{}, FFSyntax_default, ErrUnexpectedEOF},
+		{`{"a": "\`, FFLimits{}, FFSyntax_default, ErrUnexpectedEOF},
+		{`[1, /* 2 ]`, FFLimits{}, FFSyntax_default, ErrUnexpectedEOF},
+		{`[[[1]]]`, FFLimits{MaxDepth: 2}, FFSyntax_default, FFErr_limit_exceeded},
+		{`{"a": tru}`, FFLimits{}, FFSyntax_strict, FFErr_invalid_string},
+		{`{"a": [1, }, "b": 2}`, FFLimits{}, FFSyntax_default, FFErr_unexpected_token_type},
+		{`[{"a": 1]}`, FFLimits{}, FFSyntax_default, FFErr_unexpected_token_type},
+		{`[1}`, FFLimits{}, FFSyntax_default, FFErr_unexpected_token_type},
+		{strings.Repeat(`[`, 100) + `}` + strings.Repeat(`]`, 99), FFLimits{}, FFSyntax_default, FFErr_unexpected_token_type},
+		{`[1, @@@ ]`, FFLimits{}, FFSyntax_strict, FFErr_invalid_char},
+	}
+
+	for _, tt := range tests {
+		ffl := NewFFLexer([]byte(tt.input))
+		ffl.SetSyntax(tt.syntax)
+		ffl.SetLimits(tt.limits)
+		tok, _ := ffl.Scan(false)
+		if err := ffl.SkipField(tok); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.input, tt.err, err)
+		}
+	}
+}
+
+func BenchmarkSkipField(b *testing.B) {
+	input := []byte(`{"skip": {"items": [` + strings.Repeat(`{"name": "some \"quoted\" name", "n": 12345.678, "ok": true}, `, 100) + `null]}}`)
+
+	for _, bm := range []struct {
+		name   string
+		limits FFLimits
+	}{
+		{"fast", FFLimits{}},
+		{"tokens", FFLimits{MaxTokens: 1 << 30}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			ffl := NewFFLexer(input)
+			ffl.SetLimits(bm.limits)
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				ffl.Reset(input)
+				if err := ffl.ObjectEach(func(key []byte, tok FFTok) error { return nil }); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSkipFieldInvalidBytes(t *testing.T) {
+	// only the brackets are checked outside of strict syntax.
+	deep := strings.Repeat(`[{"a": `, 100) + `0` + strings.Repeat(`}]`, 100)
+	for _, value := range []string{`[1, @@@ ]`, deep} {
+		ffl := NewFFLexer([]byte(`[` + value + `, 7]`))
+		if err := skipAll(ffl); err != nil {
+			t.Errorf("%s: unexpected error: %v", value, err)
+		}
+	}
+
+	ffl := NewFFLexer([]byte(`{"a": [1, }, "b": 2}`))
+	if err := ffl.ObjectEach(func([]byte, FFTok) error { return nil }); !errors.Is(err, FFErr_unexpected_token_type) {
+		t.Errorf("expected a mismatched bracket, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jsonrt
+
+import (
+	"testing"
+)
+
+func tStrictError(t *testing.T, input string, kind FFErrKind) {
+	ffl := NewFFLexer([]byte(input))
+	ffl.SetSyntax(FFSyntax_strict)
+	for {
+		tok, err := ffl.Scan(false)
+		if err != nil {
+			ffe, ok := err.(*FFError)
+			if !ok || ffe.Kind != kind {
+				t.Fatalf("expected %v for %q, got %v", kind, input, err)
+			}
+			return
+		}
+		if tok == FFTok_eof {
+			break
+		}
+	}
+
+	if err := ffl.ExpectEOF(); err == nil {
+		t.Fatalf("expected error %v for %q", kind, input)
+	} else if ffe, ok := err.(*FFError); !ok || ffe.Kind != kind {
+		t.Fatalf("expected %v for %q, got %v", kind, input, err)
+	}
+}
+
+func TestStrictRejects(t *testing.T) {
+	tStrictError(t, `{"a": /* c */ 1}`, FFErr_unallowed_comment)
+	tStrictError(t, `{"a": 1} // c`, FFErr_unallowed_comment)
+	tStrictError(t, "{\"a\":\v1}", FFErr_invalid_char)
+	tStrictError(t, "{\"a\":\f1}", FFErr_invalid_char)
+	tStrictError(t, `{"a": 007}`, FFErr_number_leading_zero)
+	tStrictError(t, `[-01]`, FFErr_number_leading_zero)
+	tStrictError(t, "[\"\xff\"]", FFErr_string_invalid_utf8)
+}
+
+func TestStrictAccepts(t *testing.T) {
+	input := "{\"a\": [0, -0.5, 10e2, \"é\", true, null]}\r\n\t "
+	ffl := NewFFLexer([]byte(input))
+	ffl.SetSyntax(FFSyntax_strict)
+	tok, err := ffl.Scan(false)
+	if err != nil || tok != FFTok_left_bracket {
+		t.Fatalf("expected {, got %v %v", tok, err)
+	}
+	if err := ffl.SkipField(tok); err != nil {
+		t.Fatalf("SkipField failed: %v", err)
+	}
+	if err := ffl.ExpectEOF(); err != nil {
+		t.Fatalf("ExpectEOF failed: %v", err)
+	}
+}
+
+func TestExpectEOF(t *testing.T) {
+	ffl := NewFFLexer([]byte(`{} x`))
+	tok, _ := ffl.Scan(false)
+	ffl.SkipField(tok)
+	err := ffl.ExpectEOF()
+	if ffe, ok := err.(*FFError); !ok || ffe.Kind != FFErr_trailing_data {
+		t.Fatalf("expected trailing data error, got %v", err)
+	}
+
+	// comments are fine outside of strict mode.
+	ffl = NewFFLexer([]byte(`{} /* c */ // d` + "\n"))
+	tok, _ = ffl.Scan(false)
+	ffl.SkipField(tok)
+	if err := ffl.ExpectEOF(); err != nil {
+		t.Fatalf("ExpectEOF failed: %v", err)
+	}
+
+	ffl = NewFFLexer([]byte(`1 2`))
+	ffl.Scan(false)
+	err = ffl.ExpectEOF()
+	if ffe, ok := err.(*FFError); !ok || ffe.Kind != FFErr_trailing_data {
+		t.Fatalf("expected trailing data error, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"unsafe"
+)
+
+// ScanView is Scan(false) which does not copy a string without escapes
+// to Output, View returns it instead, as a slice of the input. It is meant
+// for keys and other strings which are only looked at, like matched with
+// a KeyMatcher.
+func (ffl *FFLexer) ScanView() (FFTok, error) {
+	ffl.noCopy = true
+	tok, err := ffl.Scan(false)
+	ffl.noCopy = false
+	return tok, err
+}
+
+// View returns the text of the token scanned last, which is Output unless
+// ScanView did not copy it. It must not be modified, and is only valid
+// until the next Scan: a lexer reading from an io.Reader or in push mode
+// reuses its window.
+func (ffl *FFLexer) View() []byte {
+	if ffl.hasView {
+		return ffl.view
+	}
+	return ffl.Output.Bytes()
+}
+
+// ScanStringView is ScanStringValue without copies: a string without
+// escapes is returned as a slice of the input given to NewFFLexer or
+// Reset, which stays valid as long as the input does. A string with
+// escapes, or any string of a lexer reading from an io.Reader or in push
+// mode, is copied. Either way it must not be modified, the input it may
+// share memory with neither.
+func (ffl *FFLexer) ScanStringView() ([]byte, error) {
+	ffl.noCopy = true
+	err := ffl.scanFieldValue()
+	ffl.noCopy = false
+	if err != nil {
+		return nil, err
+	}
+
+	if ffl.Token != FFTok_string {
+		return nil, &UnexpectedTokenError{Expected: FFTok_string, Got: ffl.Token}
+	}
+
+	if ffl.hasView && ffl.reader.inputView() {
+		return ffl.view, nil
+	}
+	return append([]byte(nil), ffl.View()...), nil
+}
+
+// UnsafeString returns b as a string without copying it. The string is
+// only immutable, as Go requires, while b is not modified, and keeps all
+// of the memory of b alive; use it for views of an input which is not
+// reused, like the ones of ScanStringView.
+func UnsafeString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
@@ -0,0 +1,176 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func inInput(input, view []byte) bool {
+	return len(view) > 0 && &view[0] == &input[bytes.Index(input, view)]
+}
+
+func TestScanView(t *testing.T) {
+	input := []byte(`["abc", "a\nb", 12]`)
+	ffl := NewFFLexer(input)
+
+	ffl.ScanView()
+	tok, err := ffl.ScanView()
+	if err != nil || tok != FFTok_string || string(ffl.View()) != "abc" {
+		t.Fatalf("unexpected token: %v %v %s", tok, err, ffl.View())
+	}
+	if !inInput(input, ffl.View()) || ffl.Output.Len() != 0 {
+		t.Fatalf("expected a view of the input")
+	}
+
+	if tok, _ = ffl.Peek(); tok != FFTok_comma || string(ffl.View()) != "abc" {
+		t.Fatalf("expected Peek to keep the view, got %v %s", tok, ffl.View())
+	}
+
+	ffl.ScanView()
+	tok, err = ffl.ScanView()
+	if err != nil || tok != FFTok_string || string(ffl.View()) != "a\nb" {
+		t.Fatalf("unexpected token: %v %v %q", tok, err, ffl.View())
+	}
+
+	ffl.ScanView()
+	tok, err = ffl.ScanView()
+	if err != nil || tok != FFTok_integer || string(ffl.View()) != "12" {
+		t.Fatalf("unexpected token: %v %v %s", tok, err, ffl.View())
+	}
+}
+
+func TestScanViewReaderPeek(t *testing.T) {
+	input := `{"key1"` + strings.Repeat(" ", 10000) + `: 1}`
+	ffl := NewFFLexerReader(strings.NewReader(input))
+
+	ffl.Scan(false)
+	tok, err := ffl.ScanView()
+	if err != nil || tok != FFTok_string || string(ffl.View()) != "key1" {
+		t.Fatalf("unexpected token: %v %v %s", tok, err, ffl.View())
+	}
+
+	if tok, _ = ffl.Peek(); tok != FFTok_colon || string(ffl.View()) != "key1" {
+		t.Fatalf("expected Peek to keep the view, got %v %q", tok, ffl.View())
+	}
+}
+
+func TestScanViewReaderRestore(t *testing.T) {
+	input := `["key1", ` + strings.Repeat(`1, "abcdefgh", `, ffReaderBufSize) + `1]`
+	ffl := NewFFLexerReader(strings.NewReader(input))
+
+	ffl.Scan(false)
+	if tok, err := ffl.ScanView(); err != nil || tok != FFTok_string {
+		t.Fatalf("unexpected token: %v %v", tok, err)
+	}
+
+	m := ffl.Mark()
+	for {
+		tok, err := ffl.ScanView()
+		if err != nil || tok == FFTok_right_brace {
+			break
+		}
+	}
+
+	ffl.Restore(m)
+	if string(ffl.View()) != "key1" {
+		t.Fatalf("expected Restore to bring the view back, got %q", ffl.View())
+	}
+	ffl.Release(m)
+
+	if tok, err := ffl.Scan(false); err != nil || tok != FFTok_comma {
+		t.Fatalf("unexpected token: %v %v", tok, err)
+	}
+}
+
+func TestScanViewStrict(t *testing.T) {
+	ffl := NewFFLexer([]byte("\"\xff\""))
+	ffl.SetSyntax(FFSyntax_strict)
+
+	if _, err := ffl.ScanView(); !errors.Is(err, FFErr_string_invalid_utf8) {
+		t.Fatalf("expected an invalid UTF-8 error, got %v", err)
+	}
+}
+
+func TestScanStringView(t *testing.T) {
+	input := []byte(`: "plain", : "esc\"aped"`)
+	ffl := NewFFLexer(input)
+
+	v, err := ffl.ScanStringView()
+	if err != nil || string(v) != "plain" || !inInput(input, v) {
+		t.Fatalf("expected a view of the input, got %s %v", v, err)
+	}
+	if UnsafeString(v) != "plain" {
+		t.Fatalf("unexpected string: %s", UnsafeString(v))
+	}
+
+	ffl.Scan(false)
+	v, err = ffl.ScanStringView()
+	if err != nil || string(v) != `esc"aped` {
+		t.Fatalf("unexpected string: %s %v", v, err)
+	}
+
+	ffl = NewFFLexerReader(bytes.NewReader(input))
+	v, err = ffl.ScanStringView()
+	ffl.Scan(false)
+	ffl.ScanStringView()
+	if err != nil || string(v) != "plain" {
+		t.Fatalf("expected a copy from a reader, got %s %v", v, err)
+	}
+}
+
+func TestObjectEachKeyView(t *testing.T) {
+	input := []byte(`{"first": {"x": 1}, "second": 2}`)
+	ffl := NewFFLexer(input)
+
+	var keys []string
+	err := ffl.ObjectEach(func(key []byte, tok FFTok) error {
+		if string(key) == "first" && !inInput(input, key) {
+			t.Fatalf("expected the key to be a view of the input")
+		}
+		keys = append(keys, string(key))
+		return nil
+	})
+
+	if err != nil || len(keys) != 2 || keys[0] != "first" || keys[1] != "second" {
+		t.Fatalf("unexpected keys: %v %v", keys, err)
+	}
+}
+
+func TestScanViewReset(t *testing.T) {
+	ffl := NewFFLexer([]byte(`"abc"`))
+	resets := []func(){
+		func() { ffl.Reset([]byte(`1`)) },
+		func() { ffl.ResetReader(strings.NewReader(`1`)) },
+		ffl.ResetPush,
+	}
+
+	for i, reset := range resets {
+		ffl.Reset([]byte(`"abc"`))
+		if tok, err := ffl.ScanView(); err != nil || tok != FFTok_string || string(ffl.View()) != "abc" {
+			t.Fatalf("%d: unexpected token: %v %v %s", i, tok, err, ffl.View())
+		}
+
+		reset()
+		if len(ffl.View()) != 0 || ffl.hasNum || ffl.tokStart != 0 {
+			t.Fatalf("%d: expected the reset to drop the view, got %q", i, ffl.View())
+		}
+	}
+}
@@ -0,0 +1,245 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"fmt"
+)
+
+// FieldSpec describes a field of an object for ObjectDecoder. Set is
+// called with the lexer at the token starting the value, it reads the
+// value with the Value helpers, or ObjectEach and ArrayEach. A nil Set
+// skips the value.
+type FieldSpec struct {
+	Name     string
+	Aliases  []string // other keys accepted for the field, like an old name
+	Required bool
+	Set      func(l *FFLexer, tok FFTok) error
+}
+
+// ObjectDecoder decodes objects by a table of fields, so a hand written
+// decoder only needs to declare the fields. It is built once per type and
+// safe for concurrent use, as long as its options are not changed.
+type ObjectDecoder struct {
+	// RejectUnknown makes Decode fail with an *UnknownFieldError on a key
+	// which matches no field, instead of skipping its value.
+	RejectUnknown bool
+
+	// Unknown, if set, is called for keys which match no field, so they
+	// can be collected. It reads the value like FieldSpec.Set.
+	Unknown func(l *FFLexer, key []byte, tok FFTok) error
+
+	// RejectDuplicates makes Decode fail with a *DuplicateFieldError when
+	// a field, by any of its keys, is given again. By default the last one
+	// wins: Set is called again.
+	RejectDuplicates bool
+
+	fields   []FieldSpec
+	matcher  *KeyMatcher
+	owner    []int    // field of each key of matcher
+	required []uint64 // bitset of the required fields
+
+	order []orderedKey // see SetKeyOrder
+	after []int        // for each field, where in order the next key is
+}
+
+type orderedKey struct {
+	prefix []byte // `"key":`
+	field  int
+}
+
+// NewObjectDecoder returns an ObjectDecoder for fields, matching keys
+// like encoding/json does: exactly, or else case insensitively.
+func NewObjectDecoder(fields []FieldSpec) *ObjectDecoder {
+	return newObjectDecoder(fields, NewKeyMatcher)
+}
+
+// NewExactObjectDecoder returns an ObjectDecoder for fields, which only
+// matches keys exactly.
+func NewExactObjectDecoder(fields []FieldSpec) *ObjectDecoder {
+	return newObjectDecoder(fields, NewExactKeyMatcher)
+}
+
+func newObjectDecoder(fields []FieldSpec, matcher func(names []string) *KeyMatcher) *ObjectDecoder {
+	od := &ObjectDecoder{
+		fields:   fields,
+		required: make([]uint64, (len(fields)+63)/64),
+	}
+
+	// names first, so a name wins over an alias of an earlier field.
+	var keys []string
+	for i, f := range fields {
+		keys = append(keys, f.Name)
+		od.owner = append(od.owner, i)
+
+		if f.Required {
+			od.required[i/64] |= 1 << uint(i%64)
+		}
+	}
+	for i, f := range fields {
+		for _, alias := range f.Aliases {
+			keys = append(keys, alias)
+			od.owner = append(od.owner, i)
+		}
+	}
+
+	od.matcher = matcher(keys)
+	return od
+}
+
+// SetKeyOrder tells the decoder the order keys usually come in, like the
+// one of the encoder producing the input. While the input follows it,
+// Decode compares the input against the expected `"key":` directly,
+// instead of scanning and matching each key. Keys out of order are
+// matched like before, and the order is picked up again after them.
+// Keys which are no key of a field are ignored.
+func (od *ObjectDecoder) SetKeyOrder(keys []string) {
+	od.order = od.order[:0]
+	od.after = make([]int, len(od.fields))
+	for i := range od.after {
+		od.after[i] = -1
+	}
+
+	for _, key := range keys {
+		k := od.matcher.Match([]byte(key))
+		if k < 0 {
+			continue
+		}
+
+		var prefix Buffer
+		WriteJson(&prefix, []byte(key))
+		prefix.WriteByte(':')
+
+		i := od.owner[k]
+		od.order = append(od.order, orderedKey{prefix: prefix.Bytes(), field: i})
+		od.after[i] = len(od.order)
+	}
+}
+
+// Decode decodes an object, starting at Token if it is '{', like
+// ObjectEach.
+func (od *ObjectDecoder) Decode(l *FFLexer) error {
+	var buf [1]uint64
+	seen := buf[:]
+	if len(od.required) > 1 {
+		seen = make([]uint64, len(od.required))
+	}
+
+	// next is the position in order of the key expected next.
+	next := 0
+	var fast func() (bool, error)
+	if len(od.order) > 0 {
+		fast = func() (bool, error) {
+			if next >= len(od.order) {
+				return false, nil
+			}
+
+			ok, err := l.scanKeyPrefix(od.order[next].prefix)
+			if !ok || err != nil {
+				return false, err
+			}
+
+			i := od.order[next].field
+			next++
+
+			tok, err := l.scanValue()
+			if err != nil {
+				return true, err
+			}
+			return true, l.eachValue(tok, func() error { return od.set(l, seen, i, tok) })
+		}
+	}
+
+	err := l.objectEach(fast, func(key []byte, tok FFTok) error {
+		k := od.matcher.Match(key)
+		if k < 0 {
+			if od.RejectUnknown {
+				return &UnknownFieldError{Key: string(key)}
+			}
+			if od.Unknown != nil {
+				return od.Unknown(l, key, tok)
+			}
+			return nil
+		}
+
+		i := od.owner[k]
+		if len(od.order) > 0 && od.after[i] >= 0 {
+			next = od.after[i]
+		}
+		return od.set(l, seen, i, tok)
+	})
+	if err != nil {
+		return err
+	}
+
+	for w, req := range od.required {
+		if missing := req &^ seen[w]; missing != 0 {
+			i := w * 64
+			for missing&1 == 0 {
+				missing >>= 1
+				i++
+			}
+			return &MissingFieldError{Field: od.fields[i].Name}
+		}
+	}
+
+	return nil
+}
+
+// set sets field i, tracking it in seen.
+func (od *ObjectDecoder) set(l *FFLexer, seen []uint64, i int, tok FFTok) error {
+	bit := uint64(1) << uint(i%64)
+	if seen[i/64]&bit != 0 && od.RejectDuplicates {
+		return &DuplicateFieldError{Field: od.fields[i].Name}
+	}
+	seen[i/64] |= bit
+
+	if od.fields[i].Set == nil {
+		return nil
+	}
+	return od.fields[i].Set(l, tok)
+}
+
+// MissingFieldError is returned by ObjectDecoder for a required field the
+// object does not have.
+type MissingFieldError struct {
+	Field string
+}
+
+func (e *MissingFieldError) Error() string {
+	return fmt.Sprintf("ffjson: missing required field %q", e.Field)
+}
+
+// DuplicateFieldError is returned by ObjectDecoder for a field given
+// twice, when it rejects duplicates.
+type DuplicateFieldError struct {
+	Field string
+}
+
+func (e *DuplicateFieldError) Error() string {
+	return fmt.Sprintf("ffjson: duplicate field %q", e.Field)
+}
+
+// UnknownFieldError is returned by ObjectDecoder for a key which matches
+// no field, when it rejects unknown keys.
+type UnknownFieldError struct {
+	Key string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("ffjson: unknown field %q", e.Key)
+}
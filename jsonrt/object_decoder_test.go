@@ -0,0 +1,184 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"errors"
+	"testing"
+)
+
+type testUser struct {
+	ID   int64
+	Name string
+	Tags []string
+}
+
+func TestObjectDecoder(t *testing.T) {
+	var u testUser
+	od := NewObjectDecoder([]FieldSpec{
+		{Name: "id", Required: true, Set: func(l *FFLexer, tok FFTok) (err error) {
+			u.ID, err = l.ValueInt(64)
+			return err
+		}},
+		{Name: "name", Aliases: []string{"user_name"}, Set: func(l *FFLexer, tok FFTok) (err error) {
+			u.Name, err = l.ValueString()
+			return err
+		}},
+		{Name: "tags", Set: func(l *FFLexer, tok FFTok) error {
+			return l.ArrayEach(func(i int, tok FFTok) error {
+				s, err := l.ValueString()
+				u.Tags = append(u.Tags, s)
+				return err
+			})
+		}},
+		{Name: "admin", Required: true},
+	})
+
+	err := od.Decode(NewFFLexer([]byte(`{"ID": 3, "user_name": "bob", "x": {"y": 1}, "tags": ["a"], "admin": true}`)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.ID != 3 || u.Name != "bob" || len(u.Tags) != 1 || u.Tags[0] != "a" {
+		t.Fatalf("unexpected value: %+v", u)
+	}
+
+	err = od.Decode(NewFFLexer([]byte(`{"admin": false}`)))
+	var mfe *MissingFieldError
+	if !errors.As(err, &mfe) || mfe.Field != "id" {
+		t.Fatalf("expected id to be missing, got %v", err)
+	}
+
+	u = testUser{}
+	err = od.Decode(NewFFLexer([]byte(`{"id": 1, "admin": true, "name": "a", "user_name": "b"}`)))
+	if err != nil || u.Name != "b" {
+		t.Fatalf("expected the last name to win, got %q %v", u.Name, err)
+	}
+
+	od.RejectDuplicates = true
+	err = od.Decode(NewFFLexer([]byte(`{"id": 1, "admin": true, "name": "a", "user_name": "b"}`)))
+	var dfe *DuplicateFieldError
+	if !errors.As(err, &dfe) || dfe.Field != "name" {
+		t.Fatalf("expected a duplicate name, got %v", err)
+	}
+
+	od.RejectUnknown = true
+	err = od.Decode(NewFFLexer([]byte(`{"id": 1, "x": 2}`)))
+	var ufe *UnknownFieldError
+	if !errors.As(err, &ufe) || ufe.Key != "x" {
+		t.Fatalf("expected x to be unknown, got %v", err)
+	}
+}
+
+func TestObjectDecoderCollect(t *testing.T) {
+	fields := make([]FieldSpec, 70)
+	for i := range fields {
+		fields[i].Name = string(rune('A'+i/26)) + string(rune('a'+i%26))
+	}
+	fields[69].Required = true
+
+	extra := map[string]string{}
+	od := NewExactObjectDecoder(fields)
+	od.Unknown = func(l *FFLexer, key []byte, tok FFTok) error {
+		raw, err := l.CaptureField(tok)
+		extra[string(key)] = string(raw)
+		return err
+	}
+
+	err := od.Decode(NewFFLexer([]byte(`{"Aa": 1, "aa": [1, 2], "Cr": null}`)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(extra) != 1 || extra["aa"] != "[1, 2]" {
+		t.Fatalf("unexpected unknown fields: %v", extra)
+	}
+
+	err = od.Decode(NewFFLexer([]byte(`{"Aa": 1}`)))
+	var mfe *MissingFieldError
+	if !errors.As(err, &mfe) || mfe.Field != "Cr" {
+		t.Fatalf("expected Cr to be missing, got %v", err)
+	}
+}
+
+func testOrderedDecoder(v *[4]int64) *ObjectDecoder {
+	var fields []FieldSpec
+	for i, name := range []string{"alpha", "beta", "gamma", "delta"} {
+		i := i
+		fields = append(fields, FieldSpec{Name: name, Required: true, Set: func(l *FFLexer, tok FFTok) (err error) {
+			v[i], err = l.ValueInt(64)
+			return err
+		}})
+	}
+	return NewObjectDecoder(fields)
+}
+
+func TestObjectDecoderKeyOrder(t *testing.T) {
+	var v [4]int64
+	od := testOrderedDecoder(&v)
+	od.SetKeyOrder([]string{"alpha", "beta", "gamma", "delta"})
+
+	tests := []string{
+		`{"alpha":1,"beta":2,"gamma":3,"delta":4}`,
+		`{ "alpha":1, "beta" : 2,"gamma":3 ,"delta":4 }`,
+		`{"gamma":3,"delta":4,"alpha":1,"beta":2}`,
+		`{"alpha":1,"x":{"beta":0},"beta":2,"Gamma":3,"delta":4}`,
+		`{"alpha":1,"beta":2,"delta":4,"gamma":3}`,
+	}
+
+	for _, input := range tests {
+		v = [4]int64{}
+		if err := od.Decode(NewFFLexer([]byte(input))); err != nil {
+			t.Fatalf("unexpected error for %s: %v", input, err)
+		}
+		if v != [4]int64{1, 2, 3, 4} {
+			t.Fatalf("unexpected values for %s: %v", input, v)
+		}
+	}
+
+	od.RejectDuplicates = true
+	err := od.Decode(NewFFLexer([]byte(`{"alpha":1,"beta":2,"alpha":1}`)))
+	var dfe *DuplicateFieldError
+	if !errors.As(err, &dfe) || dfe.Field != "alpha" {
+		t.Fatalf("expected a duplicate alpha, got %v", err)
+	}
+}
+
+func benchmarkObjectDecoder(b *testing.B, ordered bool) {
+	var v [4]int64
+	od := testOrderedDecoder(&v)
+	if ordered {
+		od.SetKeyOrder([]string{"alpha", "beta", "gamma", "delta"})
+	}
+
+	input := []byte(`{"alpha":1,"beta":2,"gamma":3,"delta":4}`)
+	ffl := NewFFLexer(input)
+
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		ffl.Reset(input)
+		if err := od.Decode(ffl); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkObjectDecoder(b *testing.B) {
+	benchmarkObjectDecoder(b, false)
+}
+
+func BenchmarkObjectDecoderKeyOrder(b *testing.B) {
+	benchmarkObjectDecoder(b, true)
+}
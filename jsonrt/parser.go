@@ -0,0 +1,215 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jsonrt
+
+import (
+	"io"
+)
+
+// FFParser checks the order of the tokens returned by a FFLexer, which
+// Scan does not do: Next returns the same tokens as Scan, except that
+// object keys are returned as FFTok_key, and fails on anything which is
+// not valid JSON, like `{"a" 1}` or `[1 2]`. Decoders built on Next do
+// not need to check for colons and commas themselves.
+//
+// With the JSON5 syntax trailing commas are accepted. Comments are skipped
+// unless the lexer keeps them, then they are returned as they are.
+type FFParser struct {
+	Lexer *FFLexer
+	stack []FFTok // open containers: FFTok_left_bracket or FFTok_left_brace
+	state FFParseState
+}
+
+func NewFFParser(ffl *FFLexer) *FFParser {
+	return &FFParser{
+		Lexer: ffl,
+		stack: make([]FFTok, 0, 8),
+		state: FFParse_want_value,
+	}
+}
+
+// Reset the parser to expect a new top-level value, the lexer is not
+// touched.
+func (p *FFParser) Reset() {
+	p.stack = p.stack[:0]
+	p.state = FFParse_want_value
+}
+
+// State returns what the parser expects next.
+func (p *FFParser) State() FFParseState {
+	return p.state
+}
+
+// Depth returns the number of open objects and arrays.
+func (p *FFParser) Depth() int {
+	return len(p.stack)
+}
+
+// InObject reports whether the innermost open container is an object.
+func (p *FFParser) InObject() bool {
+	return len(p.stack) > 0 && p.stack[len(p.stack)-1] == FFTok_left_bracket
+}
+
+// Next scans the next token and checks it is allowed here. At the end of
+// the input it returns FFTok_eof and io.EOF, once the top-level value is
+// complete.
+func (p *FFParser) Next() (FFTok, error) {
+	ffl := p.Lexer
+
+	for {
+		tok, err := ffl.Scan(false)
+		if tok == FFTok_eof {
+			if p.state != FFParse_end {
+				return FFTok_error, newEOFError()
+			}
+			return tok, err
+		}
+
+		if err != nil {
+			return tok, err
+		}
+
+		if tok == FFTok_comment {
+			if ffl.keepComments {
+				return tok, nil
+			}
+			continue
+		}
+
+		return p.next(tok)
+	}
+}
+
+func (p *FFParser) next(tok FFTok) (FFTok, error) {
+	switch p.state {
+	case FFParse_want_value, FFParse_array_start:
+		switch tok {
+		case FFTok_left_bracket:
+			p.stack = append(p.stack, tok)
+			p.state = FFParse_map_start
+			return tok, nil
+		case FFTok_left_brace:
+			p.stack = append(p.stack, tok)
+			p.state = FFParse_array_start
+			return tok, nil
+		case FFTok_string, FFTok_integer, FFTok_double, FFTok_bool, FFTok_null:
+			p.afterValue()
+			return tok, nil
+		case FFTok_right_brace:
+			// [] or, in JSON5, [1,]
+			if p.state == FFParse_array_start ||
+				(p.Lexer.syntax == FFSyntax_json5 && len(p.stack) > 0 && !p.InObject()) {
+				p.pop()
+				return tok, nil
+			}
+		}
+
+	case FFParse_map_start, FFParse_want_key:
+		switch tok {
+		case FFTok_string:
+			p.state = FFParse_want_colon
+			return FFTok_key, nil
+		case FFTok_right_bracket:
+			// {} or, in JSON5, {"a":1,}
+			if p.state == FFParse_map_start || p.Lexer.syntax == FFSyntax_json5 {
+				p.pop()
+				return tok, nil
+			}
+		}
+
+	case FFParse_want_colon:
+		if tok == FFTok_colon {
+			p.state = FFParse_want_value
+			return tok, nil
+		}
+
+	case FFParse_after_value:
+		switch tok {
+		case FFTok_comma:
+			if p.InObject() {
+				p.state = FFParse_want_key
+			} else {
+				p.state = FFParse_want_value
+			}
+			return tok, nil
+		case FFTok_right_bracket:
+			if p.InObject() {
+				p.pop()
+				return tok, nil
+			}
+		case FFTok_right_brace:
+			if !p.InObject() {
+				p.pop()
+				return tok, nil
+			}
+		}
+
+	case FFParse_end:
+		return FFTok_error, NewFFError(FFErr_trailing_data)
+	}
+
+	return FFTok_error, &UnexpectedTokenError{Expected: p.expected(), Got: tok}
+}
+
+// expected is the token the parser wants next in its state, FFTok_init
+// for any value.
+func (p *FFParser) expected() FFTok {
+	switch p.state {
+	case FFParse_map_start, FFParse_want_key:
+		return FFTok_string
+	case FFParse_want_colon:
+		return FFTok_colon
+	case FFParse_after_value:
+		return FFTok_comma
+	}
+	return FFTok_init
+}
+
+func (p *FFParser) pop() {
+	p.stack = p.stack[:len(p.stack)-1]
+	p.afterValue()
+}
+
+func (p *FFParser) afterValue() {
+	if len(p.stack) == 0 {
+		p.state = FFParse_end
+	} else {
+		p.state = FFParse_after_value
+	}
+}
+
+// Skip skips the rest of the value started by tok, which Next just
+// returned, checking it like Next does.
+func (p *FFParser) Skip(tok FFTok) error {
+	if tok != FFTok_left_bracket && tok != FFTok_left_brace {
+		return nil
+	}
+
+	depth := len(p.stack)
+	for len(p.stack) >= depth {
+		_, err := p.Next()
+		if err == io.EOF {
+			return newEOFError()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jsonrt
+
+import (
+	"io"
+	"testing"
+)
+
+func parseAll(input string, syntax FFSyntax) ([]FFTok, error) {
+	ffl := NewFFLexer([]byte(input))
+	ffl.SetSyntax(syntax)
+	p := NewFFParser(ffl)
+
+	var toks []FFTok
+	for {
+		tok, err := p.Next()
+		if err == io.EOF {
+			return toks, nil
+		}
+		if err != nil {
+			return toks, err
+		}
+		toks = append(toks, tok)
+	}
+}
+
+func TestParserValid(t *testing.T) {
+	toks, err := parseAll(`{"a": [1, {"b": null}, []], "c": {}} // end`, FFSyntax_default)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []FFTok{
+		FFTok_left_bracket,
+		FFTok_key, FFTok_colon, FFTok_left_brace,
+		FFTok_integer, FFTok_comma,
+		FFTok_left_bracket, FFTok_key, FFTok_colon, FFTok_null, FFTok_right_bracket, FFTok_comma,
+		FFTok_left_brace, FFTok_right_brace,
+		FFTok_right_brace, FFTok_comma,
+		FFTok_key, FFTok_colon, FFTok_left_bracket, FFTok_right_bracket,
+		FFTok_right_bracket,
+	}
+	if len(toks) != len(want) {
+		t.Fatalf("expected %v, got %v", want, toks)
+	}
+	for i := range want {
+		if toks[i] != want[i] {
+			t.Fatalf("token %d: expected %v, got %v", i, want[i], toks[i])
+		}
+	}
+
+	if _, err := parseAll(`"x"`, FFSyntax_default); err != nil {
+		t.Fatalf("unexpected error for a top-level string: %v", err)
+	}
+}
+
+func TestParserInvalid(t *testing.T) {
+	for _, input := range []string{
+		`{"a" 1}`,
+		`{"a": 1 "b": 2}`,
+		`{1: 2}`,
+		`[1 2]`,
+		`[1,]`,
+		`{"a": 1,}`,
+		`{:}`,
+		`[}`,
+		`{"a": ]`,
+		`,`,
+		`{"a": 1`,
+		`[1] [2]`,
+	} {
+		if _, err := parseAll(input, FFSyntax_default); err == nil {
+			t.Fatalf("expected an error for %s", input)
+		}
+	}
+}
+
+func TestParserJson5TrailingCommas(t *testing.T) {
+	if _, err := parseAll(`{a: [1, 2,], b: {},}`, FFSyntax_json5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := parseAll(`[,]`, FFSyntax_json5); err == nil {
+		t.Fatalf("expected an error for [,]")
+	}
+}
+
+func TestParserSkip(t *testing.T) {
+	ffl := NewFFLexer([]byte(`{"a": {"x": [1, {"y": 2}]}, "b": 3}`))
+	p := NewFFParser(ffl)
+	p.Next()
+	p.Next()
+	p.Next()
+	tok, _ := p.Next()
+	if err := p.Skip(tok); err != nil {
+		t.Fatalf("Skip failed: %v", err)
+	}
+	if p.State() != FFParse_after_value || p.Depth() != 1 {
+		t.Fatalf("unexpected state after Skip: %v %d", p.State(), p.Depth())
+	}
+	tok, _ = p.Next()
+	if tok != FFTok_comma {
+		t.Fatalf("expected comma, got %v", tok)
+	}
+	tok, _ = p.Next()
+	if tok != FFTok_key || ffl.Output.String() != "b" {
+		t.Fatalf("expected key b, got %v %s", tok, ffl.Output.String())
+	}
+}
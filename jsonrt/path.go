@@ -0,0 +1,566 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPath is returned by CompilePath for an expression it can not
+// compile.
+var ErrInvalidPath = errors.New("ffjson: invalid JSON path")
+
+// Path is a compiled JSONPath expression, evaluated over the tokens of a
+// lexer without building a tree of the document.
+type Path struct {
+	segs []pathSeg
+}
+
+type pathSelector int
+
+const (
+	selName pathSelector = iota
+	selAll
+	selIndex
+	selSlice
+	selFilter
+)
+
+// pathSeg is one step of a Path: a selector of the children of a value,
+// or of all its descendants if desc is set.
+type pathSeg struct {
+	desc bool
+	sel  pathSelector
+
+	name             string
+	start, end, step int // end is -1 for the end of the array
+	filter           *pathFilter
+}
+
+// pathFilter is a [?(...)] filter: the value at pointer in an element,
+// compared to a literal with op, or checked to exist if op is "".
+type pathFilter struct {
+	pointer string
+	op      string
+
+	tok FFTok // FFTok_double for any number
+	num float64
+	str string
+	b   bool
+}
+
+// paths are evaluated with their states in a bit set.
+const maxPathSegs = 63
+
+// CompilePath compiles a JSONPath expression. It supports a subset of
+// JSONPath:
+//
+//	$              the root
+//	.name ['name'] a child of an object
+//	.* [*]         all children of an object or array
+//	..name ..*     all descendants, or those with a name; .. goes before
+//	               brackets too, as in ..[0]
+//	[n] [a:b:c]    an element or a slice of an array, without negative
+//	               numbers
+//	[?(@.a.b>1)]   the children for which a filter holds; a filter
+//	               compares a value in the child with a number, a string,
+//	               true, false or null with == != < <= > >=, or only
+//	               checks that the value is there, as in [?(@.a)]
+func CompilePath(expr string) (*Path, error) {
+	p := &pathParser{expr: expr}
+	return p.parse()
+}
+
+// Each calls fn with each value p matches in data, in the order they start
+// in data, and the token which starts it. value is a slice of data. A
+// value may be matched inside another one, as by $..id. An error from fn
+// stops Each and is returned.
+func (p *Path) Each(data []byte, fn func(value []byte, tok FFTok) error) error {
+	return p.EachLexer(NewFFLexer(data), fn)
+}
+
+// EachLexer is Each over the next value ffl scans. For a lexer reading
+// from an io.Reader or in push mode, value is only valid until fn returns.
+func (p *Path) EachLexer(ffl *FFLexer, fn func(value []byte, tok FFTok) error) error {
+	tok, err := ffl.scanValue()
+	if err != nil {
+		return err
+	}
+	return p.visit(ffl, tok, 1, fn)
+}
+
+// visit evaluates p over the value starting with tok, the token scanned
+// last, which is reached with the segments in states done.
+func (p *Path) visit(ffl *FFLexer, tok FFTok, states uint64, fn func(value []byte, tok FFTok) error) error {
+	final := uint64(1) << uint(len(p.segs))
+	live := states &^ final
+	container := tok == FFTok_left_bracket || tok == FFTok_left_brace
+
+	if states&final != 0 {
+		if !container || live == 0 {
+			raw, err := ffl.CaptureRaw(tok)
+			if err != nil {
+				return err
+			}
+			return fn(raw, tok)
+		}
+
+		// a match with more matches inside it.
+		m := ffl.Mark()
+		raw, err := ffl.CaptureRaw(tok)
+		if err == nil {
+			err = fn(raw, tok)
+		}
+		ffl.Restore(m)
+		ffl.Release(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !container {
+		return nil
+	}
+	if live == 0 {
+		return ffl.SkipField(tok)
+	}
+
+	if tok == FFTok_left_bracket {
+		return ffl.ObjectEach(func(key []byte, tok FFTok) error {
+			next, err := p.step(ffl, live, key, -1, tok)
+			if err != nil {
+				return err
+			}
+			return p.visit(ffl, tok, next, fn)
+		})
+	}
+
+	return ffl.ArrayEach(func(i int, tok FFTok) error {
+		next, err := p.step(ffl, live, nil, i, tok)
+		if err != nil {
+			return err
+		}
+		return p.visit(ffl, tok, next, fn)
+	})
+}
+
+// step returns the states of a child, with key in an object or index in an
+// array, of a value in states. tok starts the child.
+func (p *Path) step(ffl *FFLexer, states uint64, key []byte, index int, tok FFTok) (uint64, error) {
+	var next uint64
+	for i := range p.segs {
+		bit := uint64(1) << uint(i)
+		if states&bit == 0 {
+			continue
+		}
+
+		seg := &p.segs[i]
+		if seg.desc {
+			next |= bit
+		}
+
+		ok, err := seg.match(ffl, key, index, tok)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			next |= bit << 1
+		}
+	}
+	return next, nil
+}
+
+// match reports whether the selector of seg selects a child, like step.
+func (seg *pathSeg) match(ffl *FFLexer, key []byte, index int, tok FFTok) (bool, error) {
+	switch seg.sel {
+	case selName:
+		return index < 0 && string(key) == seg.name, nil
+	case selAll:
+		return true, nil
+	case selIndex:
+		return index == seg.start, nil
+	case selSlice:
+		return index >= seg.start && (seg.end < 0 || index < seg.end) &&
+			(index-seg.start)%seg.step == 0, nil
+	case selFilter:
+		m := ffl.Mark()
+		defer ffl.Release(m)
+
+		raw, err := ffl.CaptureRaw(tok)
+		ffl.Restore(m)
+		if err != nil {
+			return false, err
+		}
+		return seg.filter.match(raw)
+	}
+	panic("not reached")
+}
+
+// match evaluates f over raw, an element.
+func (f *pathFilter) match(raw []byte) (bool, error) {
+	ffl, err := getLexer(raw, f.pointer)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if f.op == "" {
+		return true, nil
+	}
+
+	tok := ffl.Token
+	if tok == FFTok_integer {
+		tok = FFTok_double
+	}
+	if tok != f.tok {
+		return f.op == "!=", nil
+	}
+
+	c := 0
+	switch tok {
+	case FFTok_double:
+		v, err := ParseFloat(ffl.View(), 64)
+		if err != nil {
+			return false, err
+		}
+		if v < f.num {
+			c = -1
+		} else if v > f.num {
+			c = 1
+		}
+	case FFTok_string:
+		v, err := ffl.ValueString()
+		if err != nil {
+			return false, err
+		}
+		c = strings.Compare(v, f.str)
+	case FFTok_bool:
+		v, err := ffl.ValueBool()
+		if err != nil {
+			return false, err
+		}
+		if v != f.b {
+			c = 1
+		}
+		if f.op != "==" && f.op != "!=" {
+			return false, nil
+		}
+	case FFTok_null:
+		if f.op != "==" && f.op != "!=" {
+			return false, nil
+		}
+	}
+
+	switch f.op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	panic("not reached")
+}
+
+type pathParser struct {
+	expr string
+	i    int
+}
+
+func (p *pathParser) error() error {
+	return fmt.Errorf("%w: %q at offset %d", ErrInvalidPath, p.expr, p.i)
+}
+
+func (p *pathParser) eat(s string) bool {
+	if strings.HasPrefix(p.expr[p.i:], s) {
+		p.i += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *pathParser) spaces() {
+	for p.i < len(p.expr) && p.expr[p.i] == ' ' {
+		p.i++
+	}
+}
+
+func (p *pathParser) parse() (*Path, error) {
+	if !p.eat("$") {
+		return nil, p.error()
+	}
+
+	path := &Path{}
+	for p.i < len(p.expr) {
+		var seg pathSeg
+		var err error
+
+		switch {
+		case p.eat(".."):
+			seg.desc = true
+			if p.eat("[") {
+				err = p.bracket(&seg)
+			} else {
+				err = p.dotName(&seg)
+			}
+		case p.eat("."):
+			err = p.dotName(&seg)
+		case p.eat("["):
+			err = p.bracket(&seg)
+		default:
+			err = p.error()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(path.segs) == maxPathSegs {
+			return nil, p.error()
+		}
+		path.segs = append(path.segs, seg)
+	}
+	return path, nil
+}
+
+// name reads a name up to one of the bytes in stop.
+func (p *pathParser) name(stop string) string {
+	start := p.i
+	for p.i < len(p.expr) && strings.IndexByte(stop, p.expr[p.i]) < 0 {
+		p.i++
+	}
+	return p.expr[start:p.i]
+}
+
+func (p *pathParser) dotName(seg *pathSeg) error {
+	if p.eat("*") {
+		seg.sel = selAll
+		return nil
+	}
+
+	seg.name = p.name(".[")
+	if seg.name == "" {
+		return p.error()
+	}
+	return nil
+}
+
+// bracket parses a selector after its '['.
+func (p *pathParser) bracket(seg *pathSeg) error {
+	p.spaces()
+
+	switch {
+	case p.eat("*"):
+		seg.sel = selAll
+	case p.eat("?("):
+		seg.sel = selFilter
+		seg.filter = &pathFilter{}
+		if err := p.filter(seg.filter); err != nil {
+			return err
+		}
+		p.spaces()
+		if !p.eat(")") {
+			return p.error()
+		}
+	case p.i < len(p.expr) && (p.expr[p.i] == '\'' || p.expr[p.i] == '"'):
+		name, err := p.quoted()
+		if err != nil {
+			return err
+		}
+		seg.name = name
+	default:
+		if err := p.slice(seg); err != nil {
+			return err
+		}
+	}
+
+	p.spaces()
+	if !p.eat("]") {
+		return p.error()
+	}
+	return nil
+}
+
+// quoted parses a string in single or double quotes, a backslash escapes
+// the byte after it.
+func (p *pathParser) quoted() (string, error) {
+	q := p.expr[p.i]
+	p.i++
+
+	var b []byte
+	for p.i < len(p.expr) {
+		c := p.expr[p.i]
+		p.i++
+		if c == q {
+			return string(b), nil
+		}
+		if c == '\\' && p.i < len(p.expr) {
+			c = p.expr[p.i]
+			p.i++
+		}
+		b = append(b, c)
+	}
+	return "", p.error()
+}
+
+// number parses a number of digits, or returns def if there is none.
+func (p *pathParser) number(def int) (int, error) {
+	s := p.name(" :]")
+	if s == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || s[0] == '+' {
+		return 0, p.error()
+	}
+	return n, nil
+}
+
+// slice parses [n], [a:b] and [a:b:c].
+func (p *pathParser) slice(seg *pathSeg) error {
+	var err error
+	if seg.start, err = p.number(-1); err != nil {
+		return err
+	}
+
+	p.spaces()
+	if !p.eat(":") {
+		if seg.start < 0 {
+			return p.error()
+		}
+		seg.sel = selIndex
+		return nil
+	}
+
+	seg.sel = selSlice
+	if seg.start < 0 {
+		seg.start = 0
+	}
+	p.spaces()
+	if seg.end, err = p.number(-1); err != nil {
+		return err
+	}
+
+	seg.step = 1
+	p.spaces()
+	if p.eat(":") {
+		p.spaces()
+		if seg.step, err = p.number(1); err != nil {
+			return err
+		}
+		if seg.step == 0 {
+			return p.error()
+		}
+	}
+	return nil
+}
+
+// filter parses the inside of [?(...)].
+func (p *pathParser) filter(f *pathFilter) error {
+	p.spaces()
+	if !p.eat("@") {
+		return p.error()
+	}
+
+	// the path in the element, turned into a JSON pointer.
+	var pointer []string
+	for {
+		var ref string
+		switch {
+		case p.eat("."):
+			ref = p.name(" .[)=!<>")
+			if ref == "" {
+				return p.error()
+			}
+		case p.eat("["):
+			p.spaces()
+			if p.i < len(p.expr) && (p.expr[p.i] == '\'' || p.expr[p.i] == '"') {
+				var err error
+				if ref, err = p.quoted(); err != nil {
+					return err
+				}
+			} else {
+				n, err := p.number(-1)
+				if err != nil || n < 0 {
+					return p.error()
+				}
+				ref = strconv.Itoa(n)
+			}
+			p.spaces()
+			if !p.eat("]") {
+				return p.error()
+			}
+		default:
+			f.pointer = strings.Join(pointer, "")
+			return p.comparison(f)
+		}
+
+		ref = strings.Replace(ref, "~", "~0", -1)
+		ref = strings.Replace(ref, "/", "~1", -1)
+		pointer = append(pointer, "/"+ref)
+	}
+}
+
+// comparison parses the operator and the literal of a filter, if any.
+func (p *pathParser) comparison(f *pathFilter) error {
+	p.spaces()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.eat(op) {
+			f.op = op
+			break
+		}
+	}
+	if f.op == "" {
+		return nil
+	}
+
+	p.spaces()
+	switch {
+	case p.i < len(p.expr) && (p.expr[p.i] == '\'' || p.expr[p.i] == '"'):
+		s, err := p.quoted()
+		if err != nil {
+			return err
+		}
+		f.tok = FFTok_string
+		f.str = s
+	case p.eat("true"):
+		f.tok = FFTok_bool
+		f.b = true
+	case p.eat("false"):
+		f.tok = FFTok_bool
+	case p.eat("null"):
+		f.tok = FFTok_null
+	default:
+		num, err := strconv.ParseFloat(p.name(" )"), 64)
+		if err != nil {
+			return p.error()
+		}
+		f.tok = FFTok_double
+		f.num = num
+	}
+	return nil
+}
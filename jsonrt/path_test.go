@@ -0,0 +1,140 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var pathDoc = `{
+	"id": 1,
+	"orders": [
+		{"id": 2, "items": [{"sku": "a", "qty": 1}, {"sku": "b", "qty": 3}, {"sku": "c\/d", "qty": 2.5}]},
+		{"id": 3, "items": [], "note": {"id": 4}},
+		{"id": 5, "items": [{"sku": "e", "qty": 2, "gift": true}, {"sku": "f"}]}
+	],
+	"tags": ["x", "y", "z", "w"]
+}`
+
+// pathAll returns the values p matches in input, joined with spaces.
+func pathAll(t *testing.T, expr, input string) string {
+	p, err := CompilePath(expr)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", expr, err)
+	}
+
+	var out []string
+	err = p.Each([]byte(input), func(value []byte, tok FFTok) error {
+		out = append(out, string(value))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", expr, err)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`$.id`, `1`},
+		{`$['id']`, `1`},
+		{`$.orders[*].items[?(@.qty>1)].sku`, `"b" "c\/d" "e"`},
+		{`$.orders[*].items[?(@.qty == 1)].sku`, `"a"`},
+		{`$.orders[*].items[?(@.sku != 'a')].qty`, `3 2.5 2`},
+		{`$.orders[*].items[?(@.gift)].sku`, `"e"`},
+		{`$.orders[*].items[?(@.gift == true)].sku`, `"e"`},
+		{`$.orders[?(@.note.id >= 4)].id`, `3`},
+		{`$..id`, `1 2 3 4 5`},
+		{`$..note`, `{"id": 4}`},
+		{`$..items[1].sku`, `"b" "f"`},
+		{`$.tags[1]`, `"y"`},
+		{`$.tags[1:3]`, `"y" "z"`},
+		{`$.tags[:2]`, `"x" "y"`},
+		{`$.tags[::2]`, `"x" "z"`},
+		{`$.tags[9]`, ``},
+		{`$.nope.id`, ``},
+		{`$.tags.*`, `"x" "y" "z" "w"`},
+		{`$.orders[1].*`, `3 [] {"id": 4}`},
+	}
+
+	for _, tt := range tests {
+		if got := pathAll(t, tt.expr, pathDoc); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.expr, tt.want, got)
+		}
+	}
+}
+
+func TestPathNested(t *testing.T) {
+	got := pathAll(t, `$..a`, `{"a": {"a": [1, {"a": 2}]}, "b": 3}`)
+	if got != `{"a": [1, {"a": 2}]} [1, {"a": 2}] 2` {
+		t.Fatalf("unexpected matches: %s", got)
+	}
+
+	if got = pathAll(t, `$`, ` [1] `); got != `[1]` {
+		t.Fatalf("unexpected root: %s", got)
+	}
+}
+
+func TestPathErrors(t *testing.T) {
+	for _, expr := range []string{
+		``, `id`, `$.`, `$[`, `$[-1]`, `$[1`, `$['a]`, `$[?(qty>1)]`,
+		`$[?(@.qty>x)]`, `$[::0]`, `$x`,
+	} {
+		if _, err := CompilePath(expr); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("%q: expected ErrInvalidPath, got %v", expr, err)
+		}
+	}
+
+	p, _ := CompilePath(`$.a[*]`)
+	stop := errors.New("stop")
+	n := 0
+	err := p.Each([]byte(`{"a": [1, 2, 3]}`), func(value []byte, tok FFTok) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("expected Each to stop, got %v after %d", err, n)
+	}
+
+	if err = p.Each([]byte(`{"a": [1, 2`), func([]byte, FFTok) error { return nil }); !errors.Is(err, ErrUnexpectedEOF) {
+		t.Errorf("expected ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestPathReader(t *testing.T) {
+	p, err := CompilePath(`$..items[?(@.qty>1)]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out []string
+	ffl := NewFFLexerReader(iotest.OneByteReader(strings.NewReader(pathDoc)))
+	err = p.EachLexer(ffl, func(value []byte, tok FFTok) error {
+		out = append(out, string(value))
+		return nil
+	})
+	want := `{"sku": "b", "qty": 3} {"sku": "c\/d", "qty": 2.5} {"sku": "e", "qty": 2, "gift": true}`
+	if err != nil || strings.Join(out, " ") != want {
+		t.Fatalf("unexpected matches: %s %v", out, err)
+	}
+}
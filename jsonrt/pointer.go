@@ -0,0 +1,223 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidPointer is returned for a JSON Pointer which is not "" and does
+// not start with '/', or has a '~' not followed by '0' or '1'.
+var ErrInvalidPointer = errors.New("ffjson: invalid JSON pointer")
+
+// Get returns the value pointer, a JSON Pointer (RFC 6901) such as
+// "/user/address/zip", refers to in data, and the token which starts it.
+// Only the path is scanned, the values beside it are skipped. The value is
+// a slice of data, exactly as it is there. It is ErrNotFound if there is no
+// such value.
+func Get(data []byte, pointer string) ([]byte, FFTok, error) {
+	ffl, err := getLexer(data, pointer)
+	if err != nil {
+		return nil, FFTok_error, err
+	}
+
+	tok := ffl.Token
+	raw, err := ffl.CaptureRaw(tok)
+	if err != nil {
+		return nil, FFTok_error, err
+	}
+	return raw, tok, nil
+}
+
+// GetString returns the string pointer refers to in data, like Get.
+func GetString(data []byte, pointer string) (string, error) {
+	ffl, err := getLexer(data, pointer)
+	if err != nil {
+		return "", err
+	}
+	return ffl.ValueString()
+}
+
+// GetInt returns the integer pointer refers to in data, like Get.
+func GetInt(data []byte, pointer string) (int64, error) {
+	ffl, err := getLexer(data, pointer)
+	if err != nil {
+		return 0, err
+	}
+	return ffl.ValueInt(64)
+}
+
+// GetUint returns the unsigned integer pointer refers to in data, like
+// Get.
+func GetUint(data []byte, pointer string) (uint64, error) {
+	ffl, err := getLexer(data, pointer)
+	if err != nil {
+		return 0, err
+	}
+	return ffl.ValueUint(64)
+}
+
+// GetFloat returns the number pointer refers to in data, like Get.
+func GetFloat(data []byte, pointer string) (float64, error) {
+	ffl, err := getLexer(data, pointer)
+	if err != nil {
+		return 0, err
+	}
+	return ffl.ValueFloat()
+}
+
+// GetBool returns the bool pointer refers to in data, like Get.
+func GetBool(data []byte, pointer string) (bool, error) {
+	ffl, err := getLexer(data, pointer)
+	if err != nil {
+		return false, err
+	}
+	return ffl.ValueBool()
+}
+
+// getLexer returns a lexer over data which has scanned the first token of
+// the value pointer refers to.
+func getLexer(data []byte, pointer string) (*FFLexer, error) {
+	if pointer != "" && pointer[0] != '/' {
+		return nil, ErrInvalidPointer
+	}
+
+	ffl := NewFFLexer(data)
+	tok, err := ffl.scanValue()
+	if err != nil {
+		return nil, err
+	}
+
+	for pointer != "" {
+		var ref string
+		pointer = pointer[1:]
+		if i := strings.IndexByte(pointer, '/'); i >= 0 {
+			ref, pointer = pointer[:i], pointer[i:]
+		} else {
+			ref, pointer = pointer, ""
+		}
+
+		if ref, err = unescapePointer(ref); err != nil {
+			return nil, err
+		}
+
+		switch tok {
+		case FFTok_left_bracket:
+			tok, err = pointerKey(ffl, ref)
+		case FFTok_left_brace:
+			tok, err = pointerIndex(ffl, ref)
+		default:
+			err = ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ffl, nil
+}
+
+// unescapePointer turns the ~1 and ~0 of a reference token back into / and
+// ~.
+func unescapePointer(ref string) (string, error) {
+	if strings.IndexByte(ref, '~') < 0 {
+		return ref, nil
+	}
+
+	b := make([]byte, 0, len(ref))
+	for i := 0; i < len(ref); i++ {
+		c := ref[i]
+		if c == '~' {
+			if i+1 == len(ref) {
+				return "", ErrInvalidPointer
+			}
+			i++
+			switch ref[i] {
+			case '0':
+				c = '~'
+			case '1':
+				c = '/'
+			default:
+				return "", ErrInvalidPointer
+			}
+		}
+		b = append(b, c)
+	}
+	return string(b), nil
+}
+
+// pointerKey scans the object whose '{' was scanned last up to the value of
+// key, and returns the token which starts it.
+func pointerKey(ffl *FFLexer, key string) (FFTok, error) {
+	var found FFTok
+	err := ffl.ObjectEach(func(k []byte, tok FFTok) error {
+		if string(k) != key {
+			return nil
+		}
+		found = tok
+		return errStopEach
+	})
+	return pointerFound(found, err)
+}
+
+// pointerIndex scans the array whose '[' was scanned last up to the
+// element ref, a decimal index, and returns the token which starts it.
+func pointerIndex(ffl *FFLexer, ref string) (FFTok, error) {
+	n, ok := pointerIndexValue(ref)
+	if !ok {
+		return FFTok_error, ErrNotFound
+	}
+
+	var found FFTok
+	err := ffl.ArrayEach(func(i int, tok FFTok) error {
+		if i != n {
+			return nil
+		}
+		found = tok
+		return errStopEach
+	})
+	return pointerFound(found, err)
+}
+
+func pointerFound(tok FFTok, err error) (FFTok, error) {
+	if err == errStopEach {
+		return tok, nil
+	}
+	if err != nil {
+		return FFTok_error, err
+	}
+	return FFTok_error, ErrNotFound
+}
+
+// pointerIndexValue parses an array index, which has no leading zeros. "-",
+// the element after the last one, is never found.
+func pointerIndexValue(ref string) (int, bool) {
+	if ref == "" || len(ref) > 1 && ref[0] == '0' || len(ref) > 9 {
+		return 0, false
+	}
+
+	n := 0
+	for i := 0; i < len(ref); i++ {
+		c := ref[i]
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
@@ -0,0 +1,124 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"testing"
+)
+
+// the example document of RFC 6901.
+var pointerDoc = []byte(`{
+	"foo": ["bar", "baz"],
+	"": 0,
+	"a/b": 1,
+	"c%d": 2,
+	"e^f": 3,
+	"g|h": 4,
+	"i\\j": 5,
+	"k\"l": 6,
+	" ": 7,
+	"m~n": 8,
+	"user": {"name": "Ann é", "address": {"zip": "90210", "no": 12}, "tags": [{"x": true}, 1.5]}
+}`)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		pointer string
+		value   string
+		tok     FFTok
+	}{
+		{"/foo", `["bar", "baz"]`, FFTok_left_brace},
+		{"/foo/0", `"bar"`, FFTok_string},
+		{"/foo/1", `"baz"`, FFTok_string},
+		{"/", `0`, FFTok_integer},
+		{"/a~1b", `1`, FFTok_integer},
+		{"/c%d", `2`, FFTok_integer},
+		{"/e^f", `3`, FFTok_integer},
+		{"/g|h", `4`, FFTok_integer},
+		{"/i\\j", `5`, FFTok_integer},
+		{"/k\"l", `6`, FFTok_integer},
+		{"/ ", `7`, FFTok_integer},
+		{"/m~0n", `8`, FFTok_integer},
+		{"/user/address/zip", `"90210"`, FFTok_string},
+		{"/user/tags/0", `{"x": true}`, FFTok_left_bracket},
+		{"/user/tags/0/x", `true`, FFTok_bool},
+	}
+
+	for _, tt := range tests {
+		value, tok, err := Get(pointerDoc, tt.pointer)
+		if err != nil || string(value) != tt.value || tok != tt.tok {
+			t.Errorf("%q: expected %s %v, got %s %v %v", tt.pointer, tt.value, tt.tok, value, tok, err)
+		}
+	}
+
+	whole, tok, err := Get(pointerDoc, "")
+	if err != nil || string(whole) != string(pointerDoc) || tok != FFTok_left_bracket {
+		t.Errorf("expected the whole document, got %v %v", tok, err)
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	tests := []struct {
+		pointer string
+		err     error
+	}{
+		{"foo", ErrInvalidPointer},
+		{"/m~2n", ErrInvalidPointer},
+		{"/m~", ErrInvalidPointer},
+		{"/bar", ErrNotFound},
+		{"/foo/2", ErrNotFound},
+		{"/foo/-", ErrNotFound},
+		{"/foo/01", ErrNotFound},
+		{"/foo/0/x", ErrNotFound},
+		{"/user/name/0", ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := Get(pointerDoc, tt.pointer); err != tt.err {
+			t.Errorf("%q: expected %v, got %v", tt.pointer, tt.err, err)
+		}
+	}
+
+	if _, _, err := Get([]byte(`{"a": [1, `), "/a/3"); err == nil {
+		t.Errorf("expected an error for truncated input")
+	}
+}
+
+func TestGetTyped(t *testing.T) {
+	if s, err := GetString(pointerDoc, "/user/name"); err != nil || s != "Ann é" {
+		t.Errorf("unexpected name: %q %v", s, err)
+	}
+	if n, err := GetInt(pointerDoc, "/user/address/no"); err != nil || n != 12 {
+		t.Errorf("unexpected no: %d %v", n, err)
+	}
+	if n, err := GetUint(pointerDoc, "/m~0n"); err != nil || n != 8 {
+		t.Errorf("unexpected m~n: %d %v", n, err)
+	}
+	if f, err := GetFloat(pointerDoc, "/user/tags/1"); err != nil || f != 1.5 {
+		t.Errorf("unexpected tag: %v %v", f, err)
+	}
+	if b, err := GetBool(pointerDoc, "/user/tags/0/x"); err != nil || !b {
+		t.Errorf("unexpected x: %v %v", b, err)
+	}
+
+	if _, err := GetInt(pointerDoc, "/user/address/zip"); err == nil {
+		t.Errorf("expected an error for a string")
+	}
+	if _, err := GetString(pointerDoc, "/nope"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrNotFound is returned when a key looked up in a value is not there.
+var ErrNotFound = errors.New("ffjson: not found")
+
+// RawValue is the bytes of a JSON value, kept as they are in the input to
+// be decoded later, or written out again, like json.RawMessage.
+type RawValue []byte
+
+// ScanRawValue scans the next value and returns it as a RawValue.
+func (ffl *FFLexer) ScanRawValue() (RawValue, error) {
+	tok, err := ffl.scanValue()
+	if err != nil {
+		return nil, err
+	}
+	return ffl.CaptureRawValue(tok)
+}
+
+// CaptureRawValue is CaptureRaw returning a RawValue. It is a slice of the
+// input for a lexer on a []byte, and a copy otherwise.
+func (ffl *FFLexer) CaptureRawValue(start FFTok) (RawValue, error) {
+	raw, err := ffl.CaptureRaw(start)
+	if err != nil {
+		return nil, err
+	}
+	if !ffl.reader.inputView() {
+		raw = append([]byte(nil), raw...)
+	}
+	return RawValue(raw), nil
+}
+
+// Kind returns the token which starts v: FFTok_left_bracket for an
+// object, FFTok_left_brace for an array, FFTok_integer or FFTok_double for
+// a number, and so on. It is FFTok_error for anything else.
+func (v RawValue) Kind() FFTok {
+	b := bytes.TrimSpace(v)
+	if len(b) == 0 {
+		return FFTok_error
+	}
+
+	switch b[0] {
+	case '{':
+		return FFTok_left_bracket
+	case '[':
+		return FFTok_left_brace
+	case '"', '\'':
+		return FFTok_string
+	case 't', 'f':
+		return FFTok_bool
+	case 'n':
+		return FFTok_null
+	}
+	return numberTok(b)
+}
+
+// Lexer returns a new lexer over v, with the default options.
+func (v RawValue) Lexer() *FFLexer {
+	return NewFFLexer(v)
+}
+
+// Get returns the value of key in v, an object, without decoding anything
+// else, only the fields before it are scanned. It is ErrNotFound if v has
+// no such key, the first one is returned if it has several.
+func (v RawValue) Get(key string) (RawValue, error) {
+	ffl := v.Lexer()
+
+	var found RawValue
+	err := ffl.ObjectEach(func(k []byte, tok FFTok) error {
+		if string(k) != key {
+			return nil
+		}
+		var err error
+		if found, err = ffl.CaptureRawValue(tok); err != nil {
+			return err
+		}
+		return errStopEach
+	})
+
+	if err == errStopEach {
+		return found, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, ErrNotFound
+}
+
+// errStopEach ends an ObjectEach early when the field looked for is found.
+var errStopEach = errors.New("ffjson: stop")
+
+// MarshalJSONBuf writes v to buf as it is, or null if v is empty.
+func (v RawValue) MarshalJSONBuf(buf EncodingBuffer) error {
+	if len(v) == 0 {
+		buf.AppendString("null")
+	} else {
+		buf.AppendBytes(v)
+	}
+	return nil
+}
+
+// MarshalJSON returns v, or null if v is empty, so encoding/json writes it
+// as it is too.
+func (v RawValue) MarshalJSON() ([]byte, error) {
+	if len(v) == 0 {
+		return []byte("null"), nil
+	}
+	return v, nil
+}
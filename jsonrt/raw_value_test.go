@@ -0,0 +1,117 @@
+/**
+ *  Copyright 2014 Paul Querna
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+package jsonrt
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestRawValue(t *testing.T) {
+	input := []byte(`{"type": "order", "payload": {"id": 7, "tags": ["a\/b"]}}`)
+	ffl := NewFFLexer(input)
+
+	var payload RawValue
+	err := ffl.ObjectEach(func(key []byte, tok FFTok) error {
+		if string(key) == "payload" {
+			var err error
+			payload, err = ffl.CaptureRawValue(tok)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(payload) != `{"id": 7, "tags": ["a\/b"]}` {
+		t.Fatalf("unexpected payload: %s", payload)
+	}
+	if payload.Kind() != FFTok_left_bracket {
+		t.Fatalf("unexpected kind: %v", payload.Kind())
+	}
+
+	tags, err := payload.Get("tags")
+	if err != nil || string(tags) != `["a\/b"]` || tags.Kind() != FFTok_left_brace {
+		t.Fatalf("unexpected tags: %s %v", tags, err)
+	}
+	if _, err = payload.Get("name"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	id, err := payload.Get("id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l := id.Lexer()
+	l.Scan(false)
+	n, err := l.ValueInt(64)
+	if err != nil || n != 7 {
+		t.Fatalf("unexpected id: %d %v", n, err)
+	}
+
+	buf := &Buffer{}
+	payload.MarshalJSONBuf(buf)
+	RawValue(nil).MarshalJSONBuf(buf)
+	if buf.String() != string(payload)+"null" {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+
+	out, err := json.Marshal(map[string]RawValue{"p": tags})
+	if err != nil || string(out) != `{"p":["a\/b"]}` {
+		t.Fatalf("unexpected marshal: %s %v", out, err)
+	}
+}
+
+func TestRawValueKind(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  FFTok
+	}{
+		{`{}`, FFTok_left_bracket},
+		{` [1]`, FFTok_left_brace},
+		{`"x"`, FFTok_string},
+		{`true`, FFTok_bool},
+		{`null`, FFTok_null},
+		{`-12`, FFTok_integer},
+		{`1.5e3`, FFTok_double},
+		{``, FFTok_error},
+		{`1.`, FFTok_error},
+	}
+
+	for _, tt := range tests {
+		if kind := RawValue(tt.input).Kind(); kind != tt.kind {
+			t.Errorf("%q: expected %v, got %v", tt.input, tt.kind, kind)
+		}
+	}
+}
+
+func TestScanRawValueReader(t *testing.T) {
+	ffl := NewFFLexerReader(iotest.OneByteReader(strings.NewReader(`[{"a": 1}, "b"]`)))
+	ffl.Scan(false)
+
+	v, err := ffl.ScanRawValue()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ffl.Scan(false)
+	ffl.Scan(false)
+	if string(v) != `{"a": 1}` {
+		t.Fatalf("expected a copy of the value, got %s", v)
+	}
+}
@@ -26,22 +26,75 @@ import (
 
 const sliceStringMask = cIJC | cNFP
 
+// ioError wraps an error from reading the input into a FFErr_io
+// *FFError: io.EOF inside a token becomes ErrUnexpectedEOF.
+func ioError(err error) error {
+	if _, ok := err.(*FFError); ok || err == ErrNeedMore {
+		return err
+	}
+
+	if err == io.EOF {
+		return newEOFError()
+	}
+
+	return &FFError{Kind: FFErr_io, Err: err}
+}
+
+// stringError is an error from lexing a string, of the given kind.
+func stringError(kind FFErrKind, format string, args ...interface{}) error {
+	return &FFError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// initial window size of a streaming ffReader, the window only grows
+// when a single token does not fit into it.
+const ffReaderBufSize = 4096
+
+// ffReader reads from a []byte, or from an io.Reader through a sliding
+// window: s[0:l] is the part of the input currently held in memory, and
+// base is the offset of s[0] in the whole input.
 type ffReader struct {
 	s []byte
 	i int
 	l int
+
+	rd   io.Reader // nil when the whole input is in s
+	rerr error     // sticky error from rd
+	buf  []byte    // window owned by the reader, used with rd and in push mode
+	base int
+	line int // line number of s[0]
+	char int // characters on that line before s[0]
+
+	push   bool // input is handed in by feed instead of read from rd
+	closed bool // no more feed calls will follow
+	hold   int  // offset in the whole input that must stay in the window, or -1
+
+	ws    *[256]bool // whitespace table of the syntax in use
+	json5 bool       // accept the extra escapes of JSON5
+
+	maxString int // FFLimits.MaxStringBytes
+	maxInput  int // FFLimits.MaxInputBytes
 }
 
 func newffReader(d []byte) *ffReader {
 	return &ffReader{
-		s: d,
-		i: 0,
-		l: len(d),
+		s:    d,
+		i:    0,
+		l:    len(d),
+		line: 1,
+		hold: -1,
+		ws:   &whitespaceLookupTable,
 	}
 }
 
+func newffReaderIO(rd io.Reader) *ffReader {
+	r := newffReader(nil)
+	r.ResetIO(rd)
+	return r
+}
+
+// Pos returns the offset in the whole input, not in the current window.
 func (r *ffReader) Pos() int {
-	return r.i
+	return r.base + r.i
 }
 
 // Reset the reader, and add new input.
@@ -49,6 +102,162 @@ func (r *ffReader) Reset(d []byte) {
 	r.s = d
 	r.i = 0
 	r.l = len(d)
+	r.rd = nil
+	r.rerr = nil
+	r.base = 0
+	r.line = 1
+	r.char = 0
+	r.push = false
+	r.closed = false
+	r.hold = -1
+}
+
+// ResetIO resets the reader to stream its input from rd.
+func (r *ffReader) ResetIO(rd io.Reader) {
+	if r.buf == nil {
+		r.buf = make([]byte, 0, ffReaderBufSize)
+	}
+	r.Reset(r.buf[:0])
+	r.rd = rd
+}
+
+// ResetPush resets the reader to take its input from feed.
+func (r *ffReader) ResetPush() {
+	r.ResetIO(nil)
+	r.push = true
+}
+
+// feed appends p to the window of a reader in push mode, p is copied.
+func (r *ffReader) feed(p []byte) {
+	r.compact()
+	r.s = append(r.s[:r.l], p...)
+	r.l = len(r.s)
+	r.buf = r.s
+}
+
+// fill reads more input from rd into the window. Bytes before r.i,
+// except the last one so UnreadByte keeps working, are dropped first.
+// The returned shift is how far the remaining bytes moved towards the
+// start of r.s, callers holding indexes into r.s must subtract it.
+// fill returns a nil error only if at least one byte was added.
+func (r *ffReader) fill() (int, error) {
+	if r.push {
+		if r.closed {
+			return 0, io.EOF
+		}
+		return 0, ErrNeedMore
+	}
+
+	if r.rd == nil {
+		return 0, io.EOF
+	}
+
+	if r.rerr != nil {
+		return 0, r.rerr
+	}
+
+	if err := r.inputLimit(); err != nil {
+		return 0, err
+	}
+
+	shift := r.compact()
+
+	if r.l == cap(r.s) {
+		ns := make([]byte, r.l, 2*cap(r.s)+ffReaderBufSize)
+		copy(ns, r.s)
+		r.buf = ns
+		r.s = ns
+	}
+
+	// same limit as bufio, for readers returning 0, nil forever.
+	for tries := 0; tries < 100; tries++ {
+		n, err := r.rd.Read(r.s[r.l:cap(r.s)])
+		r.l += n
+		r.s = r.s[:r.l]
+		if err != nil {
+			r.rerr = err
+			if n > 0 {
+				return shift, nil
+			}
+			return shift, err
+		}
+		if n > 0 {
+			return shift, nil
+		}
+	}
+
+	r.rerr = io.ErrNoProgress
+	return shift, r.rerr
+}
+
+// inputLimit checks the input read so far against FFLimits.MaxInputBytes.
+func (r *ffReader) inputLimit() error {
+	if r.maxInput > 0 && r.base+r.l > r.maxInput {
+		return limitError("input", r.maxInput)
+	}
+	return nil
+}
+
+// stringLimit checks the bytes written to out since start, and those from
+// r.i up to j which are still to be written, against
+// FFLimits.MaxStringBytes. It is checked before out grows, so a huge
+// string is not copied first.
+func (r *ffReader) stringLimit(out *Buffer, start int, j int) error {
+	if r.maxString > 0 && out.Len()-start+j-r.i > r.maxString {
+		return limitError("string", r.maxString)
+	}
+	return nil
+}
+
+// compact drops the consumed bytes from the front of the window and
+// returns how far the remaining ones moved.
+func (r *ffReader) compact() int {
+	shift := r.i - 1
+	if r.hold >= 0 && r.hold-r.base < shift {
+		shift = r.hold - r.base
+	}
+
+	if shift <= 0 {
+		return 0
+	}
+
+	r.discard(shift)
+	return shift
+}
+
+// discard drops the first n bytes of the window, keeping track of the
+// line and character position they covered.
+func (r *ffReader) discard(n int) {
+	for _, c := range r.s[:n] {
+		r.char++
+		if c == '\n' {
+			r.line++
+			r.char = 0
+		}
+	}
+
+	copy(r.s, r.s[n:r.l])
+	r.l -= n
+	r.s = r.s[:r.l]
+	r.i -= n
+	r.base += n
+}
+
+// ensure tries to make n bytes from index j on available in the window,
+// it returns j adjusted for the shift of the window. Running into the end
+// of the input is not an error here, callers still check against r.l.
+func (r *ffReader) ensure(j int, n int) (int, error) {
+	for j+n > r.l && (r.rd != nil || r.push) {
+		shift, err := r.fill()
+		j -= shift
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return j, err
+		}
+	}
+	return j, nil
 }
 
 // Calcuates the Position with line and line offset,
@@ -56,10 +265,23 @@ func (r *ffReader) Reset(d []byte) {
 // it will iterate the buffer from the begining, and should
 // only be used in error-paths.
 func (r *ffReader) PosWithLine() (int, int) {
-	currentLine := 1
-	currentChar := 0
+	return r.LineAt(r.Pos())
+}
+
+// LineAt is PosWithLine for any offset in the whole input. Offsets which
+// are no longer in the window of a streaming reader are clamped to it.
+func (r *ffReader) LineAt(off int) (int, int) {
+	currentLine := r.line
+	currentChar := r.char
+
+	end := off - r.base
+	if end < 0 {
+		end = 0
+	} else if end > r.l {
+		end = r.l
+	}
 
-	for i := 0; i < r.i; i++ {
+	for i := 0; i < end; i++ {
 		c := r.s[i]
 		currentChar++
 		if c == '\n' {
@@ -72,13 +294,9 @@ func (r *ffReader) PosWithLine() (int, int) {
 }
 
 func (r *ffReader) ReadByteNoWS() (byte, error) {
-	if r.i >= r.l {
-		return 0, io.EOF
-	}
-
 	j := r.i
 
-	for {
+	for j < r.l {
 		c := r.s[j]
 		j++
 
@@ -96,20 +314,28 @@ func (r *ffReader) ReadByteNoWS() (byte, error) {
 				return c, nil
 			}
 		*/
-		if whitespaceLookupTable[c] == false {
+		if r.ws[c] == false {
 			r.i = j
 			return c, nil
 		}
+	}
 
-		if j >= r.l {
-			return 0, io.EOF
-		}
+	return r.readByteNoWSMore(j)
+}
+
+// readByteNoWSMore is ReadByteNoWS once the window ran out at j. Only a
+// streaming reader gets more input there, for a []byte it is the end.
+func (r *ffReader) readByteNoWSMore(j int) (byte, error) {
+	r.i = j
+	if _, err := r.fill(); err != nil {
+		return 0, err
 	}
+	return r.ReadByteNoWS()
 }
 
 func (r *ffReader) ReadByte() (byte, error) {
 	if r.i >= r.l {
-		return 0, io.EOF
+		return r.readByteMore()
 	}
 
 	r.i++
@@ -117,6 +343,16 @@ func (r *ffReader) ReadByte() (byte, error) {
 	return r.s[r.i-1], nil
 }
 
+// readByteMore is ReadByte once the window ran out, like
+// readByteNoWSMore.
+func (r *ffReader) readByteMore() (byte, error) {
+	if _, err := r.fill(); err != nil {
+		return 0, err
+	}
+	r.i++
+	return r.s[r.i-1], nil
+}
+
 func (r *ffReader) UnreadByte() {
 	if r.i <= 0 {
 		panic("ffReader.UnreadByte: at beginning of slice")
@@ -137,8 +373,7 @@ func (r *ffReader) readU4(j int) (rune, error) {
 			j++
 			continue
 		} else {
-			// TODO(pquerna): handle errors better. layering violation.
-			return -1, fmt.Errorf("lex_string_invalid_hex_char: %v %v", c, string(u4[:]))
+			return -1, stringError(FFErr_string_invalid_hex_char, "lex_string_invalid_hex_char: %v %v", c, string(u4[:]))
 		}
 	}
 
@@ -151,6 +386,11 @@ func (r *ffReader) readU4(j int) (rune, error) {
 }
 
 func (r *ffReader) handleEscaped(c byte, j int, out *Buffer) (int, error) {
+	j, err := r.ensure(j, 1)
+	if err != nil {
+		return 0, err
+	}
+
 	if j >= r.l {
 		return 0, io.EOF
 	}
@@ -159,12 +399,22 @@ func (r *ffReader) handleEscaped(c byte, j int, out *Buffer) (int, error) {
 	j++
 
 	if c == 'u' {
+		j, err = r.ensure(j, 4)
+		if err != nil {
+			return 0, err
+		}
+
 		ru, err := r.readU4(j)
 		if err != nil {
 			return 0, err
 		}
 
 		if utf16.IsSurrogate(ru) {
+			j, err = r.ensure(j, 10)
+			if err != nil {
+				return 0, err
+			}
+
 			ru2, err := r.readU4(j + 6)
 			if err != nil {
 				return 0, err
@@ -176,7 +426,7 @@ func (r *ffReader) handleEscaped(c byte, j int, out *Buffer) (int, error) {
 			if rval != unicode.ReplacementChar {
 				out.WriteRune(rval)
 			} else {
-				return 0, fmt.Errorf("lex_string_invalid_unicode_surrogate: %v %v", ru, ru2)
+				return 0, stringError(FFErr_string_invalid_escaped_char, "lex_string_invalid_unicode_surrogate: %v %v", ru, ru2)
 			}
 		} else {
 			out.Write(r.s[r.i : j-2])
@@ -186,7 +436,10 @@ func (r *ffReader) handleEscaped(c byte, j int, out *Buffer) (int, error) {
 		}
 		return j, nil
 	} else if byteLookupTable[c]&cVEC == 0 {
-		return 0, fmt.Errorf("lex_string_invalid_escaped_char: %v", c)
+		if r.json5 {
+			return r.handleEscapedJSON5(c, j, out)
+		}
+		return 0, stringError(FFErr_string_invalid_escaped_char, "lex_string_invalid_escaped_char: %v", c)
 	} else {
 		out.Write(r.s[r.i : j-2])
 		r.i = j
@@ -215,32 +468,196 @@ func (r *ffReader) handleEscaped(c byte, j int, out *Buffer) (int, error) {
 	return j, nil
 }
 
+// handleEscapedJSON5 handles the escapes JSON5 has on top of JSON: \',
+// \v, \0, \xHH, escaped line breaks, which are dropped, and any other
+// character, which stands for itself.
+func (r *ffReader) handleEscapedJSON5(c byte, j int, out *Buffer) (int, error) {
+	// nothing is written before the input is there, so a string can go on
+	// from r.i after ErrNeedMore.
+	var err error
+	switch c {
+	case '\r':
+		j, err = r.ensure(j, 1)
+	case 'x':
+		j, err = r.ensure(j, 2)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	out.Write(r.s[r.i : j-2])
+
+	switch {
+	case c == 'v':
+		out.WriteByte('\v')
+	case c == '0':
+		out.WriteByte(0)
+	case c == '\n':
+	case c == '\r':
+		if j < r.l && r.s[j] == '\n' {
+			j++
+		}
+	case c == 'x':
+		if j+2 > r.l {
+			return 0, io.EOF
+		}
+		if byteLookupTable[r.s[j]]&cVHC == 0 || byteLookupTable[r.s[j+1]]&cVHC == 0 {
+			return 0, stringError(FFErr_string_invalid_hex_char, "lex_string_invalid_hex_char: %v", string(r.s[j:j+2]))
+		}
+		rr, err := ParseUint(r.s[j:j+2], 16, 8)
+		if err != nil {
+			return 0, err
+		}
+		out.WriteRune(rune(rr))
+		j += 2
+	case c >= '1' && c <= '9':
+		return 0, stringError(FFErr_string_invalid_escaped_char, "lex_string_invalid_escaped_char: %v", c)
+	default:
+		out.WriteByte(c)
+	}
+
+	r.i = j
+	return j, nil
+}
+
+// SliceStringSingle is SliceString for the single quoted strings of JSON5.
+func (r *ffReader) SliceStringSingle(out *Buffer) error {
+	return r.sliceStringSingle(out, out.Len())
+}
+
+// sliceStringSingle is sliceString for the single quoted strings of JSON5.
+func (r *ffReader) sliceStringSingle(out *Buffer, start int) error {
+	j := r.i
+
+	for {
+		if j >= r.l {
+			if err := r.stringLimit(out, start, j); err != nil {
+				return err
+			}
+			if j > r.i {
+				out.Write(r.s[r.i:j])
+				r.i = j
+			}
+			shift, err := r.fill()
+			j -= shift
+			if err != nil {
+				return err
+			}
+		}
+
+		c := r.s[j]
+		j++
+
+		if c == '\'' {
+			if err := r.stringLimit(out, start, j-1); err != nil {
+				return err
+			}
+			out.Write(r.s[r.i : j-1])
+			r.i = j
+			return nil
+		} else if c == '\\' {
+			// the escape writes one rune at most.
+			if err := r.stringLimit(out, start, j); err != nil {
+				return err
+			}
+			var err error
+			j, err = r.handleEscaped(c, j, out)
+			if err != nil {
+				return err
+			}
+		} else if c < ' ' {
+			return stringError(FFErr_string_invalid_json_char, "lex_string_invalid_json_char: %v", c)
+		}
+	}
+}
+
+// viewString returns the rest of a string as a slice of the window and
+// moves past it, if the string has no escapes and ends in the window.
+// Otherwise it returns false, and leaves the reader as it was.
+func (r *ffReader) viewString() ([]byte, bool) {
+	j, c := aScanString(r.s[:r.l], r.i)
+	if c != '"' || r.maxString > 0 && j-1-r.i > r.maxString {
+		return nil, false
+	}
+
+	v := r.s[r.i : j-1]
+	r.i = j
+	return v, true
+}
+
+// windowIndex returns the index of v in the window, if v is a slice of
+// it.
+func (r *ffReader) windowIndex(v []byte) (int, bool) {
+	w := r.s[:cap(r.s)]
+	i := cap(w) - cap(v)
+	if len(v) == 0 || i < 0 || i+len(v) > r.l || &w[i] != &v[0] {
+		return 0, false
+	}
+	return i, true
+}
+
+// inputView reports whether slices of the window are slices of the input
+// given to Reset, which stay valid.
+func (r *ffReader) inputView() bool {
+	return r.rd == nil && !r.push
+}
+
 func (r *ffReader) SliceString(out *Buffer) error {
+	return r.sliceString(out, out.Len())
+}
+
+// sliceString is SliceString for a string whose first bytes are in out
+// from start on already. On ErrNeedMore, out holds the string up to r.i,
+// so it can go on from there after feed.
+func (r *ffReader) sliceString(out *Buffer, start int) error {
 	var c byte
 	// TODO(pquerna): string_with_escapes? de-escape here?
 	j := r.i
 
 	for {
 		if j >= r.l {
-			return io.EOF
+			if err := r.stringLimit(out, start, j); err != nil {
+				return err
+			}
+			// hand over what we have, so fill can drop it from the window.
+			if j > r.i {
+				out.Write(r.s[r.i:j])
+				r.i = j
+			}
+			shift, err := r.fill()
+			j -= shift
+			if err != nil {
+				return err
+			}
 		}
 
 		j, c = aScanString(r.s, j)
 
 		if c == '"' {
+			if err := r.stringLimit(out, start, j-1); err != nil {
+				return err
+			}
 			if j != r.i {
 				out.Write(r.s[r.i : j-1])
 				r.i = j
 			}
 			return nil
 		} else if c == '\\' {
+			// the escape writes one rune at most.
+			if err := r.stringLimit(out, start, j); err != nil {
+				return err
+			}
 			var err error
 			j, err = r.handleEscaped(c, j, out)
 			if err != nil {
 				return err
 			}
 		} else if byteLookupTable[c]&cIJC != 0 {
-			return fmt.Errorf("lex_string_invalid_json_char: %v", c)
+			if c == 0 && j >= r.l && r.s[j-1] != 0 {
+				// aScanString ran off the end of the window.
+				continue
+			}
+			return stringError(FFErr_string_invalid_json_char, "lex_string_invalid_json_char: %v", c)
 		}
 		continue
 	}
@@ -508,6 +925,15 @@ var whitespaceLookupTable [256]bool = [256]bool{
 	false, /* 255 */
 }
 
+// strictWhitespaceLookupTable only has the four whitespace bytes RFC 8259
+// allows, \v and \f are not among them.
+var strictWhitespaceLookupTable = func() [256]bool {
+	t := whitespaceLookupTable
+	t['\v'] = false
+	t['\f'] = false
+	return t
+}()
+
 /* a lookup table which lets us quickly determine three things:
  * cVEC - valid escaped control char
  * note.  the solidus '/' may be escaped or not.